@@ -0,0 +1,74 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import "time"
+
+// An Animator tracks the animated tiles of a map and reports which frame of
+// each animation to draw at a given time.
+type Animator struct {
+	anims map[GID]animation
+}
+
+type animation struct {
+	gids   []GID
+	ends   []time.Duration // Time at which each frame ends, from the start of the animation.
+	length time.Duration
+}
+
+// NewAnimator returns an Animator for all animated tiles in m's tilesets.
+// Changes to m's tilesets after the call are not reflected in the Animator.
+func NewAnimator(m *Map) *Animator {
+	a := &Animator{anims: make(map[GID]animation)}
+	for i := range m.Tilesets {
+		ts := &m.Tilesets[i]
+		for j := range ts.Tiles {
+			t := &ts.Tiles[j]
+			if len(t.Animation) == 0 {
+				continue
+			}
+			var anim animation
+			for _, f := range t.Animation {
+				anim.length += time.Duration(f.Duration) * time.Millisecond
+				anim.gids = append(anim.gids, ts.FirstGID+GID(f.TileID))
+				anim.ends = append(anim.ends, anim.length)
+			}
+			a.anims[ts.FirstGID+GID(t.ID)] = anim
+		}
+	}
+	return a
+}
+
+// Animated reports whether gid is an animated tile.
+func (a *Animator) Animated(gid GID) bool {
+	_, ok := a.anims[gid.ID()]
+	return ok
+}
+
+// GID returns the GID to draw for gid once elapsed time has passed since the
+// animation started. Animations loop, and tiles that aren't animated are returned unchanged.
+// The flip flags of gid are kept on the result.
+func (a *Animator) GID(gid GID, elapsed time.Duration) GID {
+	anim, ok := a.anims[gid.ID()]
+	if !ok {
+		return gid
+	}
+	return anim.at(elapsed) | gid.Flags()
+}
+
+func (anim *animation) at(elapsed time.Duration) GID {
+	if anim.length <= 0 {
+		return anim.gids[0]
+	}
+
+	t := elapsed % anim.length
+	if t < 0 {
+		t += anim.length
+	}
+	for i, end := range anim.ends {
+		if t < end {
+			return anim.gids[i]
+		}
+	}
+	return anim.gids[len(anim.gids)-1]
+}
@@ -0,0 +1,80 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnimator(t *testing.T) {
+	m, err := Decode(strings.NewReader(testAnimation))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	tile := m.Tile(5)
+	if tile == nil || len(tile.Animation) != 3 || tile.Animation[1] != (Frame{TileID: 5, Duration: 200}) {
+		t.Fatalf("wrong animation: %v", tile)
+	}
+
+	a := NewAnimator(m)
+	if !a.Animated(5) || a.Animated(6) {
+		t.Fatalf("wrong animated tiles")
+	}
+	tests := []struct {
+		gid     GID
+		elapsed time.Duration
+		want    GID
+	}{
+		{5, 0, 5},
+		{5, 99 * time.Millisecond, 5},
+		{5, 100 * time.Millisecond, 6},
+		{5, 299 * time.Millisecond, 6},
+		{5, 300 * time.Millisecond, 7},
+		{5, 400 * time.Millisecond, 5},
+		{5, 1150 * time.Millisecond, 7},
+		{6, time.Second, 6},
+	}
+	for _, test := range tests {
+		got := a.GID(test.gid, test.elapsed)
+		if got != test.want {
+			t.Errorf("GID(%d, %v) = %d, expected %d", test.gid, test.elapsed, got, test.want)
+		}
+	}
+}
+
+var testAnimation = `
+<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.0" orientation="orthogonal" width="1" height="1" tilewidth="16" tileheight="16">
+ <tileset firstgid="1" name="water" tilewidth="16" tileheight="16" tilecount="8" columns="8">
+  <image source="water.png" width="128" height="16"/>
+  <tile id="4">
+   <animation>
+    <frame tileid="4" duration="100"/>
+    <frame tileid="5" duration="200"/>
+    <frame tileid="6" duration="100"/>
+   </animation>
+  </tile>
+ </tileset>
+ <layer name="Water" width="1" height="1">
+  <data encoding="csv">
+5
+</data>
+ </layer>
+</map>
+`
+
+func TestAnimatorFlipped(t *testing.T) {
+	m, err := Decode(strings.NewReader(testAnimation))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	a := NewAnimator(m)
+	got := a.GID(5|FlipHorizontal, 100*time.Millisecond)
+	if got != 6|FlipHorizontal {
+		t.Fatalf("got %#x, expected %#x", uint32(got), uint32(6|FlipHorizontal))
+	}
+}
@@ -0,0 +1,126 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eaburns/eq"
+)
+
+func TestDecodeChunks(t *testing.T) {
+	want := []Chunk{
+		{X: -2, Y: -2, Width: 2, Height: 2, GIDs: []GID{1, 2, 3, 4}},
+		{X: 0, Y: -2, Width: 2, Height: 2, GIDs: []GID{5, 0, 0, 6}},
+	}
+
+	for i, data := range []string{testChunksXml, testChunksCsv, testChunksBase64, testChunksZlib, testChunksGzip} {
+		m, err := Decode(strings.NewReader(strings.Replace(testInfinite, "DATA", data, 1)))
+		if err != nil {
+			t.Fatalf("unexpected decode error for %d: %v", i, err)
+		}
+		if !m.Infinite {
+			t.Fatalf("expected infinite map for %d", i)
+		}
+		l := &m.Layers[0]
+		if l.GIDs != nil {
+			t.Fatalf("expected no GIDs for %d, got %v", i, l.GIDs)
+		}
+		if !eq.Deep(l.Chunks, want) {
+			t.Fatalf("unequal chunks for %d:\n%v\n------\n%v", i, l.Chunks, want)
+		}
+	}
+}
+
+func TestGIDAt(t *testing.T) {
+	m, err := Decode(strings.NewReader(strings.Replace(testInfinite, "DATA", testChunksCsv, 1)))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	l := &m.Layers[0]
+
+	tests := []struct {
+		x, y int
+		want GID
+	}{
+		{-2, -2, 1}, {-1, -2, 2}, {-2, -1, 3}, {-1, -1, 4},
+		{0, -2, 5}, {1, -1, 6}, {0, -1, 0},
+		{-3, -2, 0}, {0, 0, 0}, {2, -2, 0},
+	}
+	for _, test := range tests {
+		if got := l.GIDAt(test.x, test.y); got != test.want {
+			t.Errorf("GIDAt(%d, %d) = %d, expected %d", test.x, test.y, got, test.want)
+		}
+	}
+
+	fin := Layer{Width: 2, Height: 2, GIDs: []GID{1, 2, 3, 4}}
+	if fin.GIDAt(1, 1) != 4 || fin.GIDAt(2, 0) != 0 || fin.GIDAt(-1, 0) != 0 {
+		t.Fatalf("wrong GIDAt for finite layer")
+	}
+}
+
+var testInfinite = `
+<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.2" orientation="orthogonal" width="4" height="2" tilewidth="16" tileheight="16" infinite="1">
+ <tileset firstgid="1" name="land" tilewidth="16" tileheight="16">
+  <image source="tiles.png" width="96" height="16"/>
+ </tileset>
+ <layer name="Ground" width="4" height="2">
+DATA
+ </layer>
+</map>
+`
+
+var testChunksXml = `  <data>
+   <chunk x="-2" y="-2" width="2" height="2">
+    <tile gid="1"/>
+    <tile gid="2"/>
+    <tile gid="3"/>
+    <tile gid="4"/>
+   </chunk>
+   <chunk x="0" y="-2" width="2" height="2">
+    <tile gid="5"/>
+    <tile/>
+    <tile/>
+    <tile gid="6"/>
+   </chunk>
+  </data>`
+
+var testChunksCsv = `  <data encoding="csv">
+   <chunk x="-2" y="-2" width="2" height="2">
+1,2,
+3,4
+</chunk>
+   <chunk x="0" y="-2" width="2" height="2">
+5,0,
+0,6
+</chunk>
+  </data>`
+
+var testChunksBase64 = `  <data encoding="base64">
+   <chunk x="-2" y="-2" width="2" height="2">
+    AQAAAAIAAAADAAAABAAAAA==
+   </chunk>
+   <chunk x="0" y="-2" width="2" height="2">
+    BQAAAAAAAAAAAAAABgAAAA==
+   </chunk>
+  </data>`
+
+var testChunksZlib = `  <data encoding="base64" compression="zlib">
+   <chunk x="-2" y="-2" width="2" height="2">
+    eJxjZGBgYAJiZiBmAWIAAGAACw==
+   </chunk>
+   <chunk x="0" y="-2" width="2" height="2">
+    eJxjZUAANiAGAAB4AAw=
+   </chunk>
+  </data>`
+
+var testChunksGzip = `  <data encoding="base64" compression="gzip">
+   <chunk x="-2" y="-2" width="2" height="2">
+    H4sIAAAAAAACA2NkYGBgAmJmIGYBYgDv1AWvEAAAAA==
+   </chunk>
+   <chunk x="0" y="-2" width="2" height="2">
+    H4sIAAAAAAACA2NlQAA2IAYAn8mJsxAAAAA=
+   </chunk>
+  </data>`
@@ -0,0 +1,61 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import "image"
+
+// TileImage returns the image that gid is drawn from and the tile's rectangle within it.
+// It returns false if gid has no tileset or its tileset has no image for it.
+func (m *Map) TileImage(gid GID) (*Image, image.Rectangle, bool) {
+	ts := m.Tileset(gid)
+	if ts == nil {
+		return nil, image.Rectangle{}, false
+	}
+	return ts.TileImage(int32(gid.ID() - ts.FirstGID))
+}
+
+// IsCollection reports whether t is an image collection, with a separate image for each tile
+// rather than a single image for the whole tileset.
+func (t *Tileset) IsCollection() bool {
+	return !t.Image.present()
+}
+
+// TileImage returns the image that the tile with local ID id is drawn from and the tile's
+// rectangle within it. Tile IDs in image collections need not be contiguous.
+// It returns false if t has no image for id.
+func (t *Tileset) TileImage(id int32) (*Image, image.Rectangle, bool) {
+	if id < 0 {
+		return nil, image.Rectangle{}, false
+	}
+
+	if t.IsCollection() {
+		tile := t.Tile(id)
+		if tile == nil || !tile.Image.present() {
+			return nil, image.Rectangle{}, false
+		}
+		r := image.Rect(0, 0, tile.Image.Width, tile.Image.Height)
+		if tile.Width > 0 && tile.Height > 0 {
+			r = image.Rect(tile.X, tile.Y, tile.X+tile.Width, tile.Y+tile.Height)
+		}
+		return &tile.Image, r, true
+	}
+
+	if t.TileWidth <= 0 || t.TileHeight <= 0 {
+		return nil, image.Rectangle{}, false
+	}
+	cols := t.Columns
+	if cols <= 0 {
+		cols = (t.Image.Width - 2*t.Margin + t.Spacing) / (t.TileWidth + t.Spacing)
+	}
+	if cols <= 0 || GID(id) >= t.gidCount() {
+		return nil, image.Rectangle{}, false
+	}
+	x := t.Margin + int(id)%cols*(t.TileWidth+t.Spacing)
+	y := t.Margin + int(id)/cols*(t.TileHeight+t.Spacing)
+	return &t.Image, image.Rect(x, y, x+t.TileWidth, y+t.TileHeight), true
+}
+
+// present reports whether the image element was given at all.
+func (img *Image) present() bool {
+	return img.Source != "" || img.Data.Text != ""
+}
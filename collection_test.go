@@ -0,0 +1,76 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestTileImage(t *testing.T) {
+	m, err := Decode(strings.NewReader(testCollection))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	if m.Tilesets[0].IsCollection() || !m.Tilesets[1].IsCollection() {
+		t.Fatalf("wrong collection detection")
+	}
+
+	tests := []struct {
+		gid  GID
+		src  string
+		r    image.Rectangle
+		want bool
+	}{
+		{1, "land.png", image.Rect(1, 1, 17, 17), true},
+		{3, "land.png", image.Rect(37, 1, 53, 17), true},
+		{4, "land.png", image.Rect(1, 19, 17, 35), true},
+		{6 | FlipHorizontal, "land.png", image.Rect(37, 19, 53, 35), true},
+		{7, "", image.Rectangle{}, false},
+		{11, "tree.png", image.Rect(0, 0, 32, 64), true},
+		{12, "", image.Rectangle{}, false},
+		{15, "rocks.png", image.Rect(16, 0, 32, 16), true},
+		{53, "house.png", image.Rect(0, 0, 96, 80), true},
+		{0, "", image.Rectangle{}, false},
+	}
+	for _, test := range tests {
+		img, r, ok := m.TileImage(test.gid)
+		if ok != test.want {
+			t.Errorf("TileImage(%d): got %v, expected %v", test.gid, ok, test.want)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if img.Source != test.src || r != test.r {
+			t.Errorf("TileImage(%d) = %q, %v, expected %q, %v", test.gid, img.Source, r, test.src, test.r)
+		}
+	}
+}
+
+var testCollection = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.9" tiledversion="1.9.2" orientation="orthogonal" renderorder="right-down" width="2" height="1" tilewidth="16" tileheight="16" infinite="0" nextlayerid="2" nextobjectid="1">
+ <tileset firstgid="1" name="land" tilewidth="16" tileheight="16" spacing="2" margin="1" tilecount="6" columns="3">
+  <image source="land.png" width="56" height="38"/>
+ </tileset>
+ <tileset firstgid="11" name="props" tilewidth="96" tileheight="80" tilecount="3" columns="0">
+  <grid orientation="orthogonal" width="1" height="1"/>
+  <tile id="0">
+   <image width="32" height="64" source="tree.png"/>
+  </tile>
+  <tile id="4" x="16" y="0" width="16" height="16">
+   <image width="64" height="16" source="rocks.png"/>
+  </tile>
+  <tile id="42">
+   <image width="96" height="80" source="house.png"/>
+  </tile>
+ </tileset>
+ <layer id="1" name="Ground" width="2" height="1">
+  <data encoding="csv">
+1,53
+</data>
+ </layer>
+</map>
+`
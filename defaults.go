@@ -0,0 +1,81 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import "encoding/xml"
+
+// Tiled omits attributes that have their default values, so these
+// unmarshalers fill in the defaults before decoding.
+
+func (m *Map) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type tmxMap Map
+	x := tmxMap{RenderOrder: "right-down"}
+	err := d.DecodeElement(&x, &start)
+	if err != nil {
+		return err
+	}
+	*m = Map(x)
+	return nil
+}
+
+func (l *Layer) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type layer Layer
+	x := layer{Opacity: 1, Visible: true, ParallaxX: 1, ParallaxY: 1}
+	err := d.DecodeElement(&x, &start)
+	if err != nil {
+		return err
+	}
+	*l = Layer(x)
+	return nil
+}
+
+func (g *ObjectGroup) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type objectGroup ObjectGroup
+	x := objectGroup{Opacity: 1, Visible: true, ParallaxX: 1, ParallaxY: 1}
+	err := d.DecodeElement(&x, &start)
+	if err != nil {
+		return err
+	}
+	*g = ObjectGroup(x)
+	return nil
+}
+
+func (l *ImageLayer) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type imageLayer ImageLayer
+	x := imageLayer{Opacity: 1, Visible: true, ParallaxX: 1, ParallaxY: 1}
+	err := d.DecodeElement(&x, &start)
+	if err != nil {
+		return err
+	}
+	*l = ImageLayer(x)
+	return nil
+}
+
+func (g *Group) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type group Group
+	x := group{Opacity: 1, Visible: true, ParallaxX: 1, ParallaxY: 1}
+	err := d.DecodeElement(&x, &start)
+	if err != nil {
+		return err
+	}
+	*g = Group(x)
+	return nil
+}
+
+func (t *Tile) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type tile Tile
+	x := struct {
+		tile
+		// Tiled 1.9 wrote a tile's type as its class.
+		Class string `xml:"class,attr"`
+	}{tile: tile{Probability: 1}}
+	err := d.DecodeElement(&x, &start)
+	if err != nil {
+		return err
+	}
+	*t = Tile(x.tile)
+	if t.Type == "" {
+		t.Type = x.Class
+	}
+	return nil
+}
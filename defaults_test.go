@@ -0,0 +1,84 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaults(t *testing.T) {
+	m, err := Decode(strings.NewReader(testTiledDefaults))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	var states []LayerState
+	m.Walk(func(n LayerNode, s LayerState) error {
+		states = append(states, s)
+		return nil
+	})
+	want := []LayerState{
+		{Opacity: 1, Visible: true, ParallaxX: 1, ParallaxY: 1},
+		{Opacity: 0.5, Visible: true, ParallaxX: 1, ParallaxY: 1},
+		{Opacity: 0.5, Visible: true, ParallaxX: 1, ParallaxY: 1},
+		{Opacity: 1, Visible: false, ParallaxX: 1, ParallaxY: 1},
+		{Opacity: 1, Visible: true, ParallaxX: 1, ParallaxY: 1},
+	}
+	if len(states) != len(want) {
+		t.Fatalf("got %v, expected %v", states, want)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Errorf("layer %d: got %+v, expected %+v", i, states[i], want[i])
+		}
+	}
+
+	objs := m.ObjectGroups[0].Objects
+	if !objs[0].Visible || objs[1].Visible {
+		t.Errorf("wrong object visibility: %v, %v", objs[0].Visible, objs[1].Visible)
+	}
+
+	tiles := m.Tilesets[0].Tiles
+	if tiles[0].Probability != 1 || tiles[1].Probability != 0.25 {
+		t.Errorf("wrong tile probabilities: %v, %v", tiles[0].Probability, tiles[1].Probability)
+	}
+}
+
+// As saved by Tiled 1.9, which writes only the attributes that differ from their defaults.
+var testTiledDefaults = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.9" tiledversion="1.9.2" orientation="orthogonal" renderorder="right-down" width="2" height="2" tilewidth="16" tileheight="16" infinite="0" nextlayerid="6" nextobjectid="3">
+ <tileset firstgid="1" name="land" tilewidth="16" tileheight="16" tilecount="3" columns="3">
+  <image source="tiles.png" width="48" height="16"/>
+  <tile id="0">
+   <properties>
+    <property name="solid" type="bool" value="true"/>
+   </properties>
+  </tile>
+  <tile id="1" probability="0.25"/>
+ </tileset>
+ <layer id="1" name="Ground" width="2" height="2">
+  <data encoding="csv">
+1,1,
+2,1
+</data>
+ </layer>
+ <group id="2" name="Decor" opacity="0.5">
+  <imagelayer id="3" name="Clouds">
+   <image source="clouds.png" width="64" height="64"/>
+  </imagelayer>
+ </group>
+ <layer id="4" name="Secrets" width="2" height="2" visible="0">
+  <data encoding="csv">
+0,0,
+0,3
+</data>
+ </layer>
+ <objectgroup id="5" name="Objects">
+  <object id="1" name="spawn" x="8" y="8">
+   <point/>
+  </object>
+  <object id="2" name="trap" x="16" y="16" width="16" height="16" visible="0"/>
+ </objectgroup>
+</map>
+`
@@ -0,0 +1,689 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// EncodeOptions control how Encode writes tile layer data.
+type EncodeOptions struct {
+	// One of csv, base64 or xml. An empty Encoding means csv.
+	Encoding string
+
+	// One of zlib, gzip or zstd, or empty for none. Only base64 data may be compressed.
+	Compression string
+}
+
+// Encode writes m to w in TMX format. Tile layers are written from their GIDs or Chunks,
+// as set by opts. Attributes that have their default values are left out.
+// Template instances are written with all of their attributes, which override the template's.
+func Encode(w io.Writer, m *Map, opts EncodeOptions) error {
+	err := opts.check()
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	e := &encoder{x: xml.NewEncoder(w), opts: opts}
+	e.x.Indent("", " ")
+	e.encodeMap(m)
+	if e.err != nil {
+		return e.err
+	}
+	return e.x.Close()
+}
+
+// check fills in the default encoding and reports whether opts are valid.
+func (opts *EncodeOptions) check() error {
+	if opts.Encoding == "" {
+		opts.Encoding = "csv"
+	}
+	switch opts.Encoding {
+	case "csv", "xml":
+		if opts.Compression != "" {
+			return fmt.Errorf("tmx: cannot compress %s data", opts.Encoding)
+		}
+	case "base64":
+		switch opts.Compression {
+		case "", "zlib", "gzip", "zstd":
+		default:
+			return fmt.Errorf("tmx: unknown compression %q", opts.Compression)
+		}
+	default:
+		return fmt.Errorf("tmx: unknown encoding %q", opts.Encoding)
+	}
+	return nil
+}
+
+// An encoder writes TMX elements, keeping the first error that occurs.
+type encoder struct {
+	x    *xml.Encoder
+	opts EncodeOptions
+	err  error
+}
+
+func (e *encoder) start(name string, a attrs) {
+	if e.err != nil {
+		return
+	}
+	e.err = e.x.EncodeToken(xml.StartElement{Name: xml.Name{Local: name}, Attr: a})
+}
+
+func (e *encoder) end(name string) {
+	if e.err != nil {
+		return
+	}
+	e.err = e.x.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}})
+}
+
+func (e *encoder) text(s string) {
+	if e.err != nil || s == "" {
+		return
+	}
+	e.err = e.x.EncodeToken(xml.CharData(s))
+}
+
+// empty writes an element with attributes and no content.
+func (e *encoder) empty(name string, a attrs) {
+	e.start(name, a)
+	e.end(name)
+}
+
+func (e *encoder) encodeMap(m *Map) {
+	var a attrs
+	a.str("version", m.Version)
+	a.always("orientation", m.Orientation)
+	if m.RenderOrder != "right-down" {
+		a.str("renderorder", m.RenderOrder)
+	}
+	a.num("width", m.Width)
+	a.num("height", m.Height)
+	a.num("tilewidth", m.TileWidth)
+	a.num("tileheight", m.TileHeight)
+	a.int("hexsidelength", m.HexSideLength, 0)
+	a.str("staggeraxis", m.StaggerAxis)
+	a.str("staggerindex", m.StaggerIndex)
+	a.str("backgroundcolor", m.BackgroundColor)
+	a.float("parallaxoriginx", m.ParallaxOriginX, 0)
+	a.float("parallaxoriginy", m.ParallaxOriginY, 0)
+	a.str("class", m.Class)
+	a.bool("infinite", m.Infinite, false)
+	a.int("nextlayerid", m.NextLayerID, 0)
+	a.int("nextobjectid", m.NextObjectID, 0)
+
+	e.start("map", a)
+	e.encodeProperties(m.Properties)
+	for i := range m.Tilesets {
+		e.encodeTileset(&m.Tilesets[i], true)
+	}
+	e.encodeLayers(m.layerNodes())
+	e.end("map")
+}
+
+// encodeTileset writes t, as a reference to its source file if it has one.
+// Tilesets within TSX files and templates have no firstgid.
+func (e *encoder) encodeTileset(t *Tileset, firstGID bool) {
+	var a attrs
+	if firstGID {
+		a.always("firstgid", strconv.FormatUint(uint64(t.FirstGID), 10))
+	}
+	if t.Source != "" {
+		a.always("source", t.Source)
+		e.empty("tileset", a)
+		return
+	}
+	a.always("name", t.Name)
+	a.str("class", t.Class)
+	a.num("tilewidth", t.TileWidth)
+	a.num("tileheight", t.TileHeight)
+	a.int("spacing", t.Spacing, 0)
+	a.int("margin", t.Margin, 0)
+	a.num("tilecount", t.TileCount)
+	a.num("columns", t.Columns)
+
+	e.start("tileset", a)
+	if t.TileOffset != (TileOffset{}) {
+		var o attrs
+		o.num("x", t.TileOffset.X)
+		o.num("y", t.TileOffset.Y)
+		e.empty("tileoffset", o)
+	}
+	e.encodeProperties(t.Properties)
+	e.encodeImage(&t.Image)
+	if len(t.TerrainTypes) > 0 {
+		e.start("terraintypes", nil)
+		for i := range t.TerrainTypes {
+			tr := &t.TerrainTypes[i]
+			var ta attrs
+			ta.always("name", tr.Name)
+			ta.num("tile", tr.Tile)
+			e.start("terrain", ta)
+			e.encodeProperties(tr.Properties)
+			e.end("terrain")
+		}
+		e.end("terraintypes")
+	}
+	for i := range t.Tiles {
+		e.encodeTile(&t.Tiles[i])
+	}
+	if len(t.WangSets) > 0 {
+		e.start("wangsets", nil)
+		for i := range t.WangSets {
+			e.encodeWangSet(&t.WangSets[i])
+		}
+		e.end("wangsets")
+	}
+	e.end("tileset")
+}
+
+func (e *encoder) encodeTile(t *Tile) {
+	var a attrs
+	a.num("id", int(t.ID))
+	a.str("type", t.Type)
+	a.str("terrain", t.Terrain)
+	a.float32("probability", t.Probability, 1)
+	if t.Width > 0 && t.Height > 0 {
+		a.int("x", t.X, 0)
+		a.int("y", t.Y, 0)
+		a.num("width", t.Width)
+		a.num("height", t.Height)
+	}
+
+	e.start("tile", a)
+	e.encodeProperties(t.Properties)
+	e.encodeImage(&t.Image)
+	if len(t.Animation) > 0 {
+		e.start("animation", nil)
+		for _, f := range t.Animation {
+			var fa attrs
+			fa.num("tileid", int(f.TileID))
+			fa.num("duration", f.Duration)
+			e.empty("frame", fa)
+		}
+		e.end("animation")
+	}
+	e.end("tile")
+}
+
+func (e *encoder) encodeWangSet(w *WangSet) {
+	var a attrs
+	a.always("name", w.Name)
+	a.str("class", w.Class)
+	a.always("type", w.Type)
+	a.num("tile", int(w.Tile))
+
+	e.start("wangset", a)
+	e.encodeProperties(w.Properties)
+	for i := range w.Colors {
+		c := &w.Colors[i]
+		var ca attrs
+		ca.always("name", c.Name)
+		ca.str("class", c.Class)
+		ca.always("color", c.Color)
+		ca.num("tile", int(c.Tile))
+		ca.float("probability", c.Probability, 1)
+		e.start("wangcolor", ca)
+		e.encodeProperties(c.Properties)
+		e.end("wangcolor")
+	}
+	for _, t := range w.Tiles {
+		var ta attrs
+		ta.num("tileid", int(t.TileID))
+		ta.always("wangid", t.WangID.String())
+		e.empty("wangtile", ta)
+	}
+	e.end("wangset")
+}
+
+// encodeImage writes img if it was given at all, with its embedded data as it is.
+func (e *encoder) encodeImage(img *Image) {
+	if !img.present() {
+		return
+	}
+	var a attrs
+	a.str("format", img.Format)
+	a.str("source", img.Source)
+	a.str("trans", img.Trans)
+	a.int("width", img.Width, 0)
+	a.int("height", img.Height, 0)
+
+	e.start("image", a)
+	if !img.Data.empty() {
+		var da attrs
+		da.str("encoding", img.Data.Encoding)
+		da.str("compression", img.Data.Compression)
+		e.start("data", da)
+		e.text(img.Data.Text)
+		e.end("data")
+	}
+	e.end("image")
+}
+
+func (e *encoder) encodeLayers(nodes []LayerNode) {
+	for _, n := range nodes {
+		switch {
+		case n.Layer != nil:
+			e.encodeLayer(n.Layer)
+		case n.ObjectGroup != nil:
+			e.encodeObjectGroup(n.ObjectGroup)
+		case n.ImageLayer != nil:
+			e.encodeImageLayer(n.ImageLayer)
+		case n.Group != nil:
+			e.encodeGroup(n.Group)
+		}
+	}
+}
+
+func (e *encoder) encodeLayer(l *Layer) {
+	var a attrs
+	a.int("id", l.ID, 0)
+	a.str("name", l.Name)
+	a.str("class", l.Class)
+	a.num("width", l.Width)
+	a.num("height", l.Height)
+	a.float32("opacity", l.Opacity, 1)
+	a.bool("visible", l.Visible, true)
+	a.str("tintcolor", l.TintColor)
+	a.float("offsetx", l.OffsetX, 0)
+	a.float("offsety", l.OffsetY, 0)
+	a.float("parallaxx", l.ParallaxX, 1)
+	a.float("parallaxy", l.ParallaxY, 1)
+	a.bool("locked", l.Locked, false)
+
+	e.start("layer", a)
+	e.encodeProperties(l.Properties)
+	var da attrs
+	if e.opts.Encoding != "xml" {
+		da.always("encoding", e.opts.Encoding)
+	}
+	da.str("compression", e.opts.Compression)
+	e.start("data", da)
+	if l.Chunks == nil {
+		e.encodeGIDs(l.GIDs, l.Width)
+	}
+	for i := range l.Chunks {
+		c := &l.Chunks[i]
+		var ca attrs
+		ca.num("x", c.X)
+		ca.num("y", c.Y)
+		ca.num("width", c.Width)
+		ca.num("height", c.Height)
+		e.start("chunk", ca)
+		e.encodeGIDs(c.GIDs, c.Width)
+		e.end("chunk")
+	}
+	e.end("data")
+	e.end("layer")
+}
+
+// encodeGIDs writes the contents of a <data> or <chunk> element holding gids,
+// in rows of the given width.
+func (e *encoder) encodeGIDs(gids []GID, width int) {
+	if e.opts.Encoding == "xml" {
+		for _, gid := range gids {
+			var a attrs
+			if gid != 0 {
+				a.always("gid", strconv.FormatUint(uint64(gid), 10))
+			}
+			e.empty("tile", a)
+		}
+		return
+	}
+	if e.err != nil {
+		return
+	}
+	var s string
+	s, e.err = encodeGIDs(gids, width, e.opts.Encoding, e.opts.Compression)
+	e.text(s)
+}
+
+// encodeGIDs returns gids as the text of a csv or base64 layer, compressed as named by compression.
+func encodeGIDs(gids []GID, width int, encoding, compression string) (string, error) {
+	if encoding == "csv" {
+		var b strings.Builder
+		b.WriteByte('\n')
+		for i, gid := range gids {
+			b.WriteString(strconv.FormatUint(uint64(gid), 10))
+			if i == len(gids)-1 {
+				b.WriteByte('\n')
+			} else if width > 0 && (i+1)%width == 0 {
+				b.WriteString(",\n")
+			} else {
+				b.WriteByte(',')
+			}
+		}
+		return b.String(), nil
+	}
+
+	var buf bytes.Buffer
+	w, err := compress(&buf, compression)
+	if err != nil {
+		return "", err
+	}
+	err = binary.Write(w, binary.LittleEndian, gids)
+	if err != nil {
+		return "", err
+	}
+	err = w.Close()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// compress returns a writer that compresses into w as named by compression.
+func compress(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case "":
+		return nopWriteCloser{w}, nil
+	case "zlib":
+		return zlib.NewWriter(w), nil
+	case "gzip":
+		return gzip.NewWriter(w), nil
+	case "zstd":
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	}
+	return nil, fmt.Errorf("tmx: unknown compression %q", compression)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func (e *encoder) encodeObjectGroup(g *ObjectGroup) {
+	var a attrs
+	a.int("id", g.ID, 0)
+	a.str("name", g.Name)
+	a.str("class", g.Class)
+	a.str("color", g.Color)
+	a.float32("opacity", g.Opacity, 1)
+	a.bool("visible", g.Visible, true)
+	a.str("tintcolor", g.TintColor)
+	a.float("offsetx", g.OffsetX, 0)
+	a.float("offsety", g.OffsetY, 0)
+	a.float("parallaxx", g.ParallaxX, 1)
+	a.float("parallaxy", g.ParallaxY, 1)
+	a.bool("locked", g.Locked, false)
+
+	e.start("objectgroup", a)
+	e.encodeProperties(g.Properties)
+	for i := range g.Objects {
+		e.encodeObject(&g.Objects[i])
+	}
+	e.end("objectgroup")
+}
+
+func (e *encoder) encodeObject(o *Object) {
+	if o.Template != "" {
+		e.encodeInstance(o)
+		return
+	}
+
+	var a attrs
+	a.int("id", o.ID, 0)
+	a.str("name", o.Name)
+	a.str("type", o.Type)
+	if o.GID != 0 {
+		a.always("gid", strconv.FormatUint(uint64(o.GID), 10))
+	}
+	a.float("x", o.X, 0)
+	a.float("y", o.Y, 0)
+	a.float("width", o.Width, 0)
+	a.float("height", o.Height, 0)
+	a.float("rotation", o.Rotation, 0)
+	a.bool("visible", o.Visible, true)
+
+	e.start("object", a)
+	e.encodeProperties(o.Properties)
+	e.encodeShape(o)
+	e.end("object")
+}
+
+// encodeInstance writes a template instance with only what it sets itself
+// and what now differs from its template, leaving the rest to the template,
+// so that later changes to the template still reach the instance.
+func (e *encoder) encodeInstance(o *Object) {
+	in := o.instance
+	t := &Object{Visible: true}
+	if in != nil && in.template != nil {
+		t = in.template
+	}
+	override := func(name string, differs bool) bool {
+		return differs || in.sets(name)
+	}
+
+	var a attrs
+	a.int("id", o.ID, 0)
+	a.always("template", o.Template)
+	if override("name", o.Name != t.Name) {
+		a.always("name", o.Name)
+	}
+	if override("type", o.Type != t.Type) {
+		a.always("type", o.Type)
+	}
+	if override("gid", o.GID != t.GID) {
+		a.always("gid", strconv.FormatUint(uint64(o.GID), 10))
+	}
+	for _, f := range []struct {
+		name string
+		o, t float64
+	}{
+		{"x", o.X, t.X},
+		{"y", o.Y, t.Y},
+		{"width", o.Width, t.Width},
+		{"height", o.Height, t.Height},
+		{"rotation", o.Rotation, t.Rotation},
+	} {
+		if override(f.name, f.o != f.t) {
+			a.always(f.name, strconv.FormatFloat(f.o, 'g', -1, 64))
+		}
+	}
+	if override("visible", o.Visible != t.Visible) {
+		if o.Visible {
+			a.always("visible", "1")
+		} else {
+			a.always("visible", "0")
+		}
+	}
+
+	var props Properties
+	for _, p := range o.Properties {
+		tp := t.Properties.Get(p.Name)
+		if tp == nil || !reflect.DeepEqual(p, *tp) || in != nil && in.properties.Get(p.Name) != nil {
+			props = append(props, p)
+		}
+	}
+
+	e.start("object", a)
+	e.encodeProperties(props)
+	if !sameShape(o, t) {
+		e.encodeShape(o)
+	}
+	e.end("object")
+}
+
+func (e *encoder) encodeShape(o *Object) {
+	if o.Ellipse != nil {
+		e.empty("ellipse", nil)
+	}
+	if o.Point != nil {
+		e.empty("point", nil)
+	}
+	if o.Polygon != nil {
+		e.empty("polygon", attrs{{Name: xml.Name{Local: "points"}, Value: o.Polygon.String()}})
+	}
+	if o.Polylines != nil {
+		e.empty("polyline", attrs{{Name: xml.Name{Local: "points"}, Value: o.Polylines.String()}})
+	}
+	if o.Text != nil {
+		e.encodeText(o.Text)
+	}
+}
+
+// sameShape reports whether a and b have the same shape.
+func sameShape(a, b *Object) bool {
+	return (a.Ellipse != nil) == (b.Ellipse != nil) &&
+		(a.Point != nil) == (b.Point != nil) &&
+		reflect.DeepEqual(a.Polygon, b.Polygon) &&
+		reflect.DeepEqual(a.Polylines, b.Polylines) &&
+		reflect.DeepEqual(a.Text, b.Text)
+}
+
+func (e *encoder) encodeText(t *Text) {
+	var a attrs
+	if t.FontFamily != "sans-serif" {
+		a.str("fontfamily", t.FontFamily)
+	}
+	a.int("pixelsize", t.PixelSize, 16)
+	a.bool("wrap", t.Wrap, false)
+	if t.Color != "#000000" {
+		a.str("color", t.Color)
+	}
+	a.bool("bold", t.Bold, false)
+	a.bool("italic", t.Italic, false)
+	a.bool("underline", t.Underline, false)
+	a.bool("strikeout", t.Strikeout, false)
+	a.bool("kerning", t.Kerning, true)
+	if t.HAlign != "left" {
+		a.str("halign", t.HAlign)
+	}
+	if t.VAlign != "top" {
+		a.str("valign", t.VAlign)
+	}
+
+	e.start("text", a)
+	e.text(t.Contents)
+	e.end("text")
+}
+
+func (e *encoder) encodeImageLayer(l *ImageLayer) {
+	var a attrs
+	a.int("id", l.ID, 0)
+	a.str("name", l.Name)
+	a.str("class", l.Class)
+	a.float32("opacity", l.Opacity, 1)
+	a.bool("visible", l.Visible, true)
+	a.str("tintcolor", l.TintColor)
+	a.float("offsetx", l.OffsetX, 0)
+	a.float("offsety", l.OffsetY, 0)
+	a.float("parallaxx", l.ParallaxX, 1)
+	a.float("parallaxy", l.ParallaxY, 1)
+	a.bool("locked", l.Locked, false)
+
+	e.start("imagelayer", a)
+	e.encodeProperties(l.Properties)
+	e.encodeImage(&l.Image)
+	e.end("imagelayer")
+}
+
+func (e *encoder) encodeGroup(g *Group) {
+	var a attrs
+	a.int("id", g.ID, 0)
+	a.str("name", g.Name)
+	a.str("class", g.Class)
+	a.float32("opacity", g.Opacity, 1)
+	a.bool("visible", g.Visible, true)
+	a.str("tintcolor", g.TintColor)
+	a.float("offsetx", g.OffsetX, 0)
+	a.float("offsety", g.OffsetY, 0)
+	a.float("parallaxx", g.ParallaxX, 1)
+	a.float("parallaxy", g.ParallaxY, 1)
+	a.bool("locked", g.Locked, false)
+
+	e.start("group", a)
+	e.encodeProperties(g.Properties)
+	e.encodeLayers(g.Layers)
+	e.end("group")
+}
+
+func (e *encoder) encodeProperties(ps Properties) {
+	if len(ps) == 0 {
+		return
+	}
+	e.start("properties", nil)
+	for i := range ps {
+		p := &ps[i]
+		var a attrs
+		a.always("name", p.Name)
+		a.str("type", p.Type)
+		a.str("propertytype", p.PropertyType)
+		// Like Tiled, write multiline strings as the element's text.
+		multiline := strings.Contains(p.Value, "\n")
+		if p.Type != "class" && !multiline {
+			a.always("value", p.Value)
+		}
+		e.start("property", a)
+		if multiline {
+			e.text(p.Value)
+		}
+		e.encodeProperties(p.Properties)
+		e.end("property")
+	}
+	e.end("properties")
+}
+
+// attrs builds the attributes of an element, leaving out those with default values.
+type attrs []xml.Attr
+
+func (a *attrs) always(name, value string) {
+	*a = append(*a, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+}
+
+func (a *attrs) str(name, value string) {
+	if value != "" {
+		a.always(name, value)
+	}
+}
+
+// num writes an integer that has no default.
+func (a *attrs) num(name string, value int) {
+	a.always(name, strconv.Itoa(value))
+}
+
+func (a *attrs) int(name string, value, def int) {
+	if value != def {
+		a.always(name, strconv.Itoa(value))
+	}
+}
+
+func (a *attrs) float(name string, value, def float64) {
+	if value != def {
+		a.always(name, strconv.FormatFloat(value, 'g', -1, 64))
+	}
+}
+
+func (a *attrs) float32(name string, value, def float32) {
+	if value != def {
+		a.always(name, strconv.FormatFloat(float64(value), 'g', -1, 32))
+	}
+}
+
+func (a *attrs) bool(name string, value, def bool) {
+	if value != def {
+		if value {
+			a.always(name, "1")
+		} else {
+			a.always(name, "0")
+		}
+	}
+}
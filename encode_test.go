@@ -0,0 +1,280 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/eaburns/eq"
+)
+
+func TestEncode(t *testing.T) {
+	docs := []string{
+		testCsv, testTMXFeatures, testGroups, testParallax, testProperties, testShapes,
+		testAnimation, testCollection, testHexagonal, testWang, testFlipped, testProjectMap,
+		strings.Replace(testPoly, "POINTS", "0,0 32,0 32,32.5", 1),
+		strings.Replace(testEmbeddedImage, "DATA", testImageZlib, 1),
+		strings.Replace(testInfinite, "DATA", testChunksCsv, 1),
+	}
+	opts := []EncodeOptions{
+		{},
+		{Encoding: "xml"},
+		{Encoding: "base64"},
+		{Encoding: "base64", Compression: "zlib"},
+		{Encoding: "base64", Compression: "gzip"},
+		{Encoding: "base64", Compression: "zstd"},
+	}
+
+	for i, doc := range docs {
+		want, err := Decode(strings.NewReader(doc))
+		if err != nil {
+			t.Fatalf("unexpected decode error for %d: %v", i, err)
+		}
+		for _, o := range opts {
+			var b bytes.Buffer
+			err = Encode(&b, want, o)
+			if err != nil {
+				t.Fatalf("unexpected encode error for %d with %+v: %v", i, o, err)
+			}
+			m, err := Decode(&b)
+			if err != nil {
+				t.Fatalf("unexpected decode error for %d with %+v: %v", i, o, err)
+			}
+			if !eq.Deep(m, want) {
+				t.Fatalf("unequal %d with %+v:\n%+v\n------\n%+v", i, o, m, want)
+			}
+		}
+	}
+}
+
+func TestEncodeDefaults(t *testing.T) {
+	m, err := Decode(strings.NewReader(testTiledDefaults))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	var b bytes.Buffer
+	err = Encode(&b, m, EncodeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+	s := b.String()
+	for _, attr := range []string{`visible="1"`, `opacity="1"`, `probability="1"`, `renderorder=`, `infinite=`} {
+		if strings.Contains(s, attr) {
+			t.Errorf("expected no %s in:\n%s", attr, s)
+		}
+	}
+	if !strings.HasPrefix(s, `<?xml version="1.0" encoding="UTF-8"?>`) {
+		t.Errorf("expected XML header in:\n%s", s)
+	}
+}
+
+func TestEncodeCsv(t *testing.T) {
+	m := &Map{
+		Version:     "1.10",
+		Orientation: "orthogonal",
+		RenderOrder: "right-down",
+		Width:       3,
+		Height:      2,
+		TileWidth:   16,
+		TileHeight:  16,
+		Layers: []Layer{
+			{ID: 1, Name: "Ground", Width: 3, Height: 2, Opacity: 1, Visible: true, ParallaxX: 1, ParallaxY: 1, GIDs: []GID{1, 2, 3, 4, 5, 6 | FlipHorizontal}},
+		},
+	}
+	var b bytes.Buffer
+	err := Encode(&b, m, EncodeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" width="3" height="2" tilewidth="16" tileheight="16">
+ <layer id="1" name="Ground" width="3" height="2">
+  <data encoding="csv">
+1,2,3,
+4,5,2147483654
+</data>
+ </layer>
+</map>`
+	if b.String() != want {
+		t.Fatalf("unexpected output:\n%s\n------\n%s", b.String(), want)
+	}
+}
+
+func TestEncodeTemplates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"maps/level.tmx":     {Data: []byte(testHiddenTemplateMap)},
+		"templates/ghost.tx": {Data: []byte(testGhostTx)},
+	}
+	want, err := DecodeFile("maps/level.tmx", FS(fsys))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	o := want.ObjectGroups[0].Objects[0]
+	if o.Name != "" || o.Type != "spirit" || !o.Visible || o.Rotation != 0 || o.Width != 8 {
+		t.Fatalf("wrong template instance: %+v", o)
+	}
+
+	var b bytes.Buffer
+	err = Encode(&b, want, EncodeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+	fsys["maps/level.tmx"] = &fstest.MapFile{Data: b.Bytes()}
+	m, err := DecodeFile("maps/level.tmx", FS(fsys))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if !eq.Deep(m, want) {
+		t.Fatalf("unequal:\n%+v\n------\n%+v", m, want)
+	}
+}
+
+func TestEncodeTemplateChanges(t *testing.T) {
+	fsys := fstest.MapFS{
+		"maps/level.tmx":     {Data: []byte(testHiddenTemplateMap)},
+		"templates/ghost.tx": {Data: []byte(testGhostTx)},
+	}
+	m, err := DecodeFile("maps/level.tmx", FS(fsys))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	m.ObjectGroups[0].Objects[0].Height = 10
+	m.ObjectGroups[0].Objects[0].Properties = Properties{{Name: "scary", Type: "bool", Value: "true"}}
+
+	var b bytes.Buffer
+	err = Encode(&b, m, EncodeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+	out := b.String()
+	if strings.Contains(out, `type="spirit"`) || strings.Contains(out, `width="8"`) || !strings.Contains(out, `height="10"`) {
+		t.Fatalf("expected only the overridden attributes:\n%s", out)
+	}
+
+	// Changes to the template reach the instance, except where the instance overrides them.
+	fsys["maps/level.tmx"] = &fstest.MapFile{Data: b.Bytes()}
+	fsys["templates/ghost.tx"] = &fstest.MapFile{Data: []byte(testWraithTx)}
+	m, err = DecodeFile("maps/level.tmx", FS(fsys))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	o := m.ObjectGroups[0].Objects[0]
+	want := Object{
+		ID: 1, Type: "wraith", Width: 12, Height: 10, Visible: true, Template: "../templates/ghost.tx",
+		Properties: Properties{{Name: "scary", Type: "bool", Value: "true"}},
+	}
+	if got := withoutInstances([]Object{o})[0]; !eq.Deep(got, want) {
+		t.Fatalf("unequal:\n%+v\n------\n%+v", got, want)
+	}
+}
+
+func TestEncodeLayerTree(t *testing.T) {
+	m, err := Decode(strings.NewReader(testGroups))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	// The flat slices are views, so layers appended to them aren't part of the map.
+	m.Layers = append(m.Layers, Layer{Name: "Ignored", Width: 2, Height: 2})
+	m.Walk(func(n LayerNode, s LayerState) error {
+		if n.Layer != nil && n.Layer.Name == "Ground" {
+			n.Layer.Name = "Earth"
+		}
+		return nil
+	})
+	m.LayerTree = m.LayerTree[:len(m.LayerTree)-1]
+	world := m.LayerTree[1].Group
+	world.Layers = append(world.Layers, LayerNode{Layer: &Layer{
+		ID: 7, Name: "Extra", Width: 2, Height: 2, Opacity: 1, Visible: true, ParallaxX: 1, ParallaxY: 1, GIDs: []GID{1, 2, 3, 0},
+	}})
+	if id := m.NewLayerID(); id != 8 {
+		t.Errorf("got new layer ID %d, expected 8", id)
+	}
+
+	var b, jb bytes.Buffer
+	err = Encode(&b, m, EncodeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+	err = EncodeJSON(&jb, m, EncodeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected JSON encode error: %v", err)
+	}
+	x, err := Decode(&b)
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	jx, err := DecodeJSON(&jb)
+	if err != nil {
+		t.Fatalf("unexpected JSON decode error: %v", err)
+	}
+
+	want := []string{"Sky", "World", "Earth", "Hidden", "Things", "Extra"}
+	for _, got := range []*Map{x, jx} {
+		var names []string
+		got.Walk(func(n LayerNode, s LayerState) error {
+			switch {
+			case n.Layer != nil:
+				names = append(names, n.Layer.Name)
+			case n.ObjectGroup != nil:
+				names = append(names, n.ObjectGroup.Name)
+			case n.ImageLayer != nil:
+				names = append(names, n.ImageLayer.Name)
+			case n.Group != nil:
+				names = append(names, n.Group.Name)
+			}
+			return nil
+		})
+		if !eq.Deep(names, want) {
+			t.Errorf("got layers %v, expected %v", names, want)
+		}
+	}
+	if l := x.Layers[len(x.Layers)-1]; !eq.Deep(l.GIDs, []GID{1, 2, 3, 0}) {
+		t.Errorf("wrong GIDs for the added layer: %v", l.GIDs)
+	}
+}
+
+func TestBadEncode(t *testing.T) {
+	m, err := Decode(strings.NewReader(testCsv))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	for _, o := range []EncodeOptions{
+		{Encoding: "csv", Compression: "zlib"},
+		{Encoding: "xml", Compression: "gzip"},
+		{Encoding: "base64", Compression: "lzma"},
+		{Encoding: "binary"},
+	} {
+		err = Encode(new(bytes.Buffer), m, o)
+		if err == nil {
+			t.Errorf("expected error for %+v", o)
+		}
+	}
+}
+
+var testHiddenTemplateMap = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.2" orientation="orthogonal" width="10" height="10" tilewidth="16" tileheight="16" nextobjectid="2">
+ <objectgroup id="1" name="Spirits">
+  <object id="1" template="../templates/ghost.tx" name="" x="0" y="0" rotation="0" visible="1"/>
+ </objectgroup>
+</map>
+`
+
+var testGhostTx = `<?xml version="1.0" encoding="UTF-8"?>
+<template>
+ <object name="ghost" type="spirit" width="8" height="8" rotation="45" visible="0"/>
+</template>
+`
+
+// testWraithTx is testGhostTx after some changes.
+var testWraithTx = `<?xml version="1.0" encoding="UTF-8"?>
+<template>
+ <object name="ghost" type="wraith" width="12" height="12" rotation="45" visible="0">
+  <properties>
+   <property name="scary" type="bool" value="false"/>
+  </properties>
+ </object>
+</template>
+`
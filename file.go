@@ -0,0 +1,104 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"unicode"
+)
+
+// DecodeFile decodes the map named name, opened through res, in either TMX
+// or Tiled's JSON format, whichever the file contains.
+// External tilesets and object templates are loaded through res as well,
+// relative to name, and merged into the map.
+func DecodeFile(name string, res Resolver) (*Map, error) {
+	f, err := res.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("tmx: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var m *Map
+	if isJSON(r) {
+		m, err = DecodeJSON(r)
+	} else {
+		m, err = Decode(r)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tmx: %s: %w", name, err)
+	}
+
+	for i := range m.Tilesets {
+		err = m.Tilesets[i].load(name, res)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = m.applyTemplates(name, res)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// isJSON reports whether the document in r looks like JSON rather than XML.
+func isJSON(r *bufio.Reader) bool {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return false
+		}
+		if !unicode.IsSpace(rune(c)) && c != '\xef' && c != '\xbb' && c != '\xbf' {
+			r.UnreadByte()
+			return c == '{'
+		}
+	}
+}
+
+// DecodeTileset decodes an external tileset (TSX) file.
+func DecodeTileset(r io.Reader) (*Tileset, error) {
+	ts := new(Tileset)
+	err := xml.NewDecoder(r).Decode(ts)
+	if err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// load replaces an external tileset reference with the contents of the tileset file,
+// in either TSX or JSON format, keeping the map's FirstGID and Source. Image sources
+// within the tileset remain relative to the tileset file.
+func (t *Tileset) load(from string, res Resolver) error {
+	if t.Source == "" {
+		return nil
+	}
+
+	name := resolvePath(from, t.Source)
+	f, err := res.Open(name)
+	if err != nil {
+		return fmt.Errorf("tmx: %s: tileset %s: %w", from, t.Source, err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var ts *Tileset
+	if isJSON(r) {
+		ts, err = DecodeJSONTileset(r)
+	} else {
+		ts, err = DecodeTileset(r)
+	}
+	if err != nil {
+		return fmt.Errorf("tmx: %s: %w", name, err)
+	}
+
+	ts.FirstGID = t.FirstGID
+	ts.Source = t.Source
+	*t = *ts
+	return nil
+}
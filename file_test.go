@@ -0,0 +1,65 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/eaburns/eq"
+)
+
+func TestDecodeFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"maps/land.tmx":     {Data: []byte(strings.Replace(testCsv, testEmbeddedTileset, testExternalTileset, 1))},
+		"tilesets/land.tsx": {Data: []byte(testTsx)},
+	}
+
+	m, err := DecodeFile("maps/land.tmx", FS(fsys))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	want, err := Decode(strings.NewReader(testCsv))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	want.Tilesets[0].Source = "../tilesets/land.tsx"
+
+	if !eq.Deep(m, want) {
+		t.Fatalf("unequal:\n%v\n------\n%v", m, want)
+	}
+}
+
+func TestDecodeFileMissingTileset(t *testing.T) {
+	fsys := fstest.MapFS{
+		"land.tmx": {Data: []byte(strings.Replace(testCsv, testEmbeddedTileset, testExternalTileset, 1))},
+	}
+
+	_, err := DecodeFile("land.tmx", FS(fsys))
+	if err == nil {
+		t.Fatalf("expected error for missing tileset")
+	}
+	if !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), "land.tmx: tileset ../tilesets/land.tsx:") {
+		t.Fatalf("wrong error for missing tileset: %v", err)
+	}
+
+	_, err = DecodeFile("missing.tmx", FS(fsys))
+	if !errors.Is(err, fs.ErrNotExist) || !strings.HasPrefix(err.Error(), "tmx: ") {
+		t.Fatalf("wrong error for missing map: %v", err)
+	}
+}
+
+var testEmbeddedTileset = ` <tileset firstgid="1" name="land" tilewidth="16" tileheight="16">
+  <image source="tiles.png" width="48" height="16"/>
+ </tileset>`
+
+var testExternalTileset = ` <tileset firstgid="1" source="../tilesets/land.tsx"/>`
+
+var testTsx = `<?xml version="1.0" encoding="UTF-8"?>
+<tileset name="land" tilewidth="16" tileheight="16">
+ <image source="tiles.png" width="48" height="16"/>
+</tileset>
+`
@@ -0,0 +1,42 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+// A GID is a global tile ID, as used in layer data and tile objects.
+// Its high bits are flags for how the tile is flipped or rotated.
+type GID uint32
+
+const (
+	FlipHorizontal GID = 1 << 31
+	FlipVertical   GID = 1 << 30
+	FlipDiagonal   GID = 1 << 29
+	RotateHex120   GID = 1 << 28 // For hexagonal maps, in place of FlipDiagonal.
+
+	flagMask = FlipHorizontal | FlipVertical | FlipDiagonal | RotateHex120
+)
+
+// ID returns g without its flags.
+func (g GID) ID() GID {
+	return g &^ flagMask
+}
+
+// Flags returns only the flag bits of g.
+func (g GID) Flags() GID {
+	return g & flagMask
+}
+
+func (g GID) FlippedHorizontally() bool {
+	return g&FlipHorizontal != 0
+}
+
+func (g GID) FlippedVertically() bool {
+	return g&FlipVertical != 0
+}
+
+func (g GID) FlippedDiagonally() bool {
+	return g&FlipDiagonal != 0
+}
+
+func (g GID) RotatedHex120() bool {
+	return g&RotateHex120 != 0
+}
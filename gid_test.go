@@ -0,0 +1,65 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGIDFlags(t *testing.T) {
+	m, err := Decode(strings.NewReader(testFlipped))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	gids := m.Layers[0].GIDs
+	want := []GID{2, 2 | FlipHorizontal, 2 | FlipVertical, 2 | FlipDiagonal | FlipHorizontal}
+	if len(gids) != len(want) {
+		t.Fatalf("got %v, expected %v", gids, want)
+	}
+	for i := range want {
+		if gids[i] != want[i] {
+			t.Errorf("GID %d: got %#x, expected %#x", i, uint32(gids[i]), uint32(want[i]))
+		}
+		if gids[i].ID() != 2 {
+			t.Errorf("GID %d: got ID %d, expected 2", i, gids[i].ID())
+		}
+	}
+
+	if !gids[1].FlippedHorizontally() || gids[1].FlippedVertically() || gids[1].FlippedDiagonally() {
+		t.Errorf("wrong flags for %#x", uint32(gids[1]))
+	}
+	if !gids[2].FlippedVertically() || gids[2].FlippedHorizontally() {
+		t.Errorf("wrong flags for %#x", uint32(gids[2]))
+	}
+	if !gids[3].FlippedDiagonally() || !gids[3].FlippedHorizontally() || gids[3].RotatedHex120() {
+		t.Errorf("wrong flags for %#x", uint32(gids[3]))
+	}
+	if m.Tile(gids[3]) == nil {
+		t.Errorf("expected tile lookup to ignore flags")
+	}
+
+	o := m.ObjectGroups[0].Objects[0]
+	if o.GID.ID() != 3 || o.GID.Flags() != FlipVertical|RotateHex120 {
+		t.Errorf("wrong object GID %#x", uint32(o.GID))
+	}
+}
+
+var testFlipped = `
+<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.0" orientation="orthogonal" width="4" height="1" tilewidth="16" tileheight="16">
+ <tileset firstgid="1" name="land" tilewidth="16" tileheight="16">
+  <image source="tiles.png" width="48" height="16"/>
+  <tile id="1" probability="0.5"/>
+ </tileset>
+ <layer name="Ground" width="4" height="1">
+  <data encoding="csv">
+2,2147483650,1073741826,2684354562
+</data>
+ </layer>
+ <objectgroup name="Things">
+  <object gid="1342177283" x="0" y="16" width="16" height="16"/>
+ </objectgroup>
+</map>
+`
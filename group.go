@@ -0,0 +1,218 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import "encoding/xml"
+
+// A LayerNode is an entry in a map's layer tree. Exactly one of its fields is set.
+type LayerNode struct {
+	Layer       *Layer
+	ObjectGroup *ObjectGroup
+	ImageLayer  *ImageLayer
+	Group       *Group
+}
+
+func (n *LayerNode) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	switch start.Name.Local {
+	case "layer":
+		n.Layer = new(Layer)
+		return d.DecodeElement(n.Layer, &start)
+	case "objectgroup":
+		n.ObjectGroup = new(ObjectGroup)
+		return d.DecodeElement(n.ObjectGroup, &start)
+	case "imagelayer":
+		n.ImageLayer = new(ImageLayer)
+		return d.DecodeElement(n.ImageLayer, &start)
+	case "group":
+		n.Group = new(Group)
+		return d.DecodeElement(n.Group, &start)
+	}
+	// Some other element, such as <editorsettings>. Decode drops the empty node.
+	return d.Skip()
+}
+
+func (n *LayerNode) empty() bool {
+	return n.Layer == nil && n.ObjectGroup == nil && n.ImageLayer == nil && n.Group == nil
+}
+
+type Group struct {
+	ID        int     `xml:"id,attr"`
+	Name      string  `xml:"name,attr"`
+	Class     string  `xml:"class,attr"`
+	Opacity   float32 `xml:"opacity,attr"`
+	Visible   bool    `xml:"visible,attr"`
+	TintColor string  `xml:"tintcolor,attr"`
+	OffsetX   float64 `xml:"offsetx,attr"`
+	OffsetY   float64 `xml:"offsety,attr"`
+	ParallaxX float64 `xml:"parallaxx,attr"`
+	ParallaxY float64 `xml:"parallaxy,attr"`
+	Locked    bool    `xml:"locked,attr"`
+
+	Properties Properties `xml:"properties>property"`
+
+	// The group's children in drawing order.
+	Layers []LayerNode `xml:",any"`
+}
+
+// LayerState is how a layer is to be drawn, after taking its parent groups into account.
+type LayerState struct {
+	Opacity   float32
+	Visible   bool
+	OffsetX   float64
+	OffsetY   float64
+	ParallaxX float64
+	ParallaxY float64
+}
+
+// Walk calls fn for each node of the layer tree in drawing order,
+// groups before their children, along with the node's effective state.
+// If fn returns an error, Walk stops and returns that error.
+func (m *Map) Walk(fn func(n LayerNode, s LayerState) error) error {
+	root := LayerState{Opacity: 1, Visible: true, ParallaxX: 1, ParallaxY: 1}
+	return walkLayers(m.layerNodes(), root, fn)
+}
+
+// layerNodes returns m's layer tree, or a tree of its layers of each kind
+// if it has none, as for a Map built by hand.
+func (m *Map) layerNodes() []LayerNode {
+	if m.LayerTree != nil {
+		return m.LayerTree
+	}
+	// Without a tree, the order of layers of different kinds is unknown.
+	var nodes []LayerNode
+	for i := range m.Layers {
+		nodes = append(nodes, LayerNode{Layer: &m.Layers[i]})
+	}
+	for i := range m.ObjectGroups {
+		nodes = append(nodes, LayerNode{ObjectGroup: &m.ObjectGroups[i]})
+	}
+	for i := range m.ImageLayers {
+		nodes = append(nodes, LayerNode{ImageLayer: &m.ImageLayers[i]})
+	}
+	return nodes
+}
+
+func walkLayers(nodes []LayerNode, parent LayerState, fn func(LayerNode, LayerState) error) error {
+	for _, n := range nodes {
+		var s LayerState
+		switch {
+		case n.Layer != nil:
+			l := n.Layer
+			s = parent.child(l.Opacity, l.Visible, l.OffsetX, l.OffsetY, l.ParallaxX, l.ParallaxY)
+		case n.ObjectGroup != nil:
+			g := n.ObjectGroup
+			s = parent.child(g.Opacity, g.Visible, g.OffsetX, g.OffsetY, g.ParallaxX, g.ParallaxY)
+		case n.ImageLayer != nil:
+			l := n.ImageLayer
+			s = parent.child(l.Opacity, l.Visible, l.OffsetX, l.OffsetY, l.ParallaxX, l.ParallaxY)
+		case n.Group != nil:
+			g := n.Group
+			s = parent.child(g.Opacity, g.Visible, g.OffsetX, g.OffsetY, g.ParallaxX, g.ParallaxY)
+		}
+
+		err := fn(n, s)
+		if err != nil {
+			return err
+		}
+		if n.Group != nil {
+			err = walkLayers(n.Group.Layers, s, fn)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// child returns the state of a layer with the given attributes within a group of state s.
+// Offsets add up and parallax factors multiply, as in Tiled.
+func (s LayerState) child(opacity float32, visible bool, offsetX, offsetY, parallaxX, parallaxY float64) LayerState {
+	return LayerState{
+		Opacity:   s.Opacity * opacity,
+		Visible:   s.Visible && visible,
+		OffsetX:   s.OffsetX + offsetX,
+		OffsetY:   s.OffsetY + offsetY,
+		ParallaxX: s.ParallaxX * parallaxX,
+		ParallaxY: s.ParallaxY * parallaxY,
+	}
+}
+
+// ScreenOffset returns how far a layer in state s is to be moved when drawn,
+// in pixels, with the camera centered on the map pixel cameraX, cameraY.
+// This is the layer's offset plus its parallax scrolling relative to the
+// map's parallax origin, the same as in Tiled's editor.
+func (m *Map) ScreenOffset(s LayerState, cameraX, cameraY float64) (x, y float64) {
+	x = s.OffsetX + (1-s.ParallaxX)*(cameraX-m.ParallaxOriginX)
+	y = s.OffsetY + (1-s.ParallaxY)*(cameraY-m.ParallaxOriginY)
+	return x, y
+}
+
+// buildLayers drops non-layer nodes from the layer tree, decodes tile data,
+// and fills in the map's per-kind layer slices.
+func (m *Map) buildLayers() error {
+	var counts [4]int
+	var err error
+	m.LayerTree, err = pruneLayers(m.LayerTree, &counts)
+	if err != nil {
+		return err
+	}
+
+	m.Layers = make([]Layer, 0, counts[0])
+	m.ObjectGroups = make([]ObjectGroup, 0, counts[1])
+	m.ImageLayers = make([]ImageLayer, 0, counts[2])
+	m.Groups = make([]Group, 0, counts[3])
+	m.shareLayers(m.LayerTree)
+	return nil
+}
+
+func pruneLayers(nodes []LayerNode, counts *[4]int) ([]LayerNode, error) {
+	var pruned []LayerNode
+	for _, n := range nodes {
+		switch {
+		case n.empty():
+			continue
+		case n.Layer != nil:
+			err := n.Layer.decodeIDs()
+			if err != nil {
+				return nil, err
+			}
+			counts[0]++
+		case n.ObjectGroup != nil:
+			counts[1]++
+		case n.ImageLayer != nil:
+			counts[2]++
+		case n.Group != nil:
+			var err error
+			n.Group.Layers, err = pruneLayers(n.Group.Layers, counts)
+			if err != nil {
+				return nil, err
+			}
+			counts[3]++
+		}
+		pruned = append(pruned, n)
+	}
+	return pruned, nil
+}
+
+// shareLayers moves each layer in nodes into the map's slices, which must
+// have room for them all, and points the nodes at the moved layers.
+func (m *Map) shareLayers(nodes []LayerNode) {
+	for i := range nodes {
+		n := &nodes[i]
+		switch {
+		case n.Layer != nil:
+			m.Layers = append(m.Layers, *n.Layer)
+			n.Layer = &m.Layers[len(m.Layers)-1]
+		case n.ObjectGroup != nil:
+			m.ObjectGroups = append(m.ObjectGroups, *n.ObjectGroup)
+			n.ObjectGroup = &m.ObjectGroups[len(m.ObjectGroups)-1]
+		case n.ImageLayer != nil:
+			m.ImageLayers = append(m.ImageLayers, *n.ImageLayer)
+			n.ImageLayer = &m.ImageLayers[len(m.ImageLayers)-1]
+		case n.Group != nil:
+			m.Groups = append(m.Groups, *n.Group)
+			n.Group = &m.Groups[len(m.Groups)-1]
+			m.shareLayers(n.Group.Layers)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLayerTree(t *testing.T) {
+	m, err := Decode(strings.NewReader(testGroups))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	if len(m.Layers) != 2 || len(m.ObjectGroups) != 1 || len(m.ImageLayers) != 1 || len(m.Groups) != 2 {
+		t.Fatalf("wrong layer counts: %d %d %d %d", len(m.Layers), len(m.ObjectGroups), len(m.ImageLayers), len(m.Groups))
+	}
+	if len(m.LayerTree) != 3 {
+		t.Fatalf("expected 3 top-level nodes, got %d", len(m.LayerTree))
+	}
+	if m.LayerTree[0].ImageLayer != &m.ImageLayers[0] || m.LayerTree[1].Group != &m.Groups[0] || m.LayerTree[2].Layer != &m.Layers[1] {
+		t.Fatalf("top-level nodes don't share storage with the layer slices")
+	}
+	if len(m.Layers[0].GIDs) != 4 {
+		t.Fatalf("expected decoded GIDs in a grouped layer, got %v", m.Layers[0].GIDs)
+	}
+
+	type visit struct {
+		name string
+		s    LayerState
+	}
+	var got []visit
+	err = m.Walk(func(n LayerNode, s LayerState) error {
+		var name string
+		switch {
+		case n.Layer != nil:
+			name = n.Layer.Name
+		case n.ObjectGroup != nil:
+			name = n.ObjectGroup.Name
+		case n.ImageLayer != nil:
+			name = n.ImageLayer.Name
+		case n.Group != nil:
+			name = n.Group.Name
+		}
+		got = append(got, visit{name, s})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected walk error: %v", err)
+	}
+
+	want := []visit{
+		{"Sky", LayerState{1, true, 0, 0, 1, 1}},
+		{"World", LayerState{0.5, true, 10, 20, 1, 1}},
+		{"Ground", LayerState{0.5, true, 10, 20, 1, 1}},
+		{"Hidden", LayerState{0.25, false, 15, 20, 1, 1}},
+		{"Things", LayerState{0.25, false, 15, 20, 1, 1}},
+		{"Top", LayerState{1, true, 0, 0, 1, 1}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, expected %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("visit %d: got %v, expected %v", i, got[i], want[i])
+		}
+	}
+}
+
+var testGroups = `
+<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.0" orientation="orthogonal" width="2" height="2" tilewidth="16" tileheight="16">
+ <editorsettings>
+  <export target="out.json" format="json"/>
+ </editorsettings>
+ <tileset firstgid="1" name="land" tilewidth="16" tileheight="16">
+  <image source="tiles.png" width="48" height="16"/>
+ </tileset>
+ <imagelayer name="Sky" opacity="1" visible="1">
+  <image source="sky.png" width="32" height="32"/>
+ </imagelayer>
+ <group name="World" offsetx="10" offsety="20" opacity="0.5" visible="1">
+  <layer name="Ground" width="2" height="2" opacity="1" visible="1">
+   <data encoding="csv">
+1,2,
+3,1
+</data>
+  </layer>
+  <group name="Hidden" offsetx="5" opacity="0.5" visible="0">
+   <objectgroup name="Things" opacity="1" visible="1">
+    <object type="thing" x="0" y="0" width="16" height="16"/>
+   </objectgroup>
+  </group>
+ </group>
+ <layer name="Top" width="2" height="2" opacity="1" visible="1">
+  <data encoding="csv">
+0,0,
+0,0
+</data>
+ </layer>
+</map>
+`
+
+func TestParallax(t *testing.T) {
+	m, err := Decode(strings.NewReader(testParallax))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if m.ParallaxOriginX != 64 || m.ParallaxOriginY != -32 || m.Class != "Level" {
+		t.Errorf("wrong map attributes: %v, %v, %q", m.ParallaxOriginX, m.ParallaxOriginY, m.Class)
+	}
+	l := &m.ImageLayers[0]
+	if l.ID != 1 || l.Class != "Backdrop" || l.TintColor != "#ff8080" || !l.Locked || l.OffsetX != 3 || l.OffsetY != 0 {
+		t.Errorf("wrong image layer attributes: %+v", l)
+	}
+	if g := &m.Groups[0]; g.Class != "" || g.TintColor != "" || g.Locked {
+		t.Errorf("wrong group attributes: %+v", g)
+	}
+	if g := &m.ObjectGroups[0]; g.OffsetX != -1 || g.OffsetY != 2 || g.ParallaxX != 1 || g.ParallaxY != 0.25 {
+		t.Errorf("wrong object group attributes: %+v", g)
+	}
+
+	var states []LayerState
+	m.Walk(func(n LayerNode, s LayerState) error {
+		states = append(states, s)
+		return nil
+	})
+	want := []LayerState{
+		{Opacity: 1, Visible: true, OffsetX: 3, ParallaxX: 0.5, ParallaxY: 0},
+		{Opacity: 1, Visible: true, OffsetX: 10, ParallaxX: 0.5, ParallaxY: 0.5},
+		{Opacity: 1, Visible: true, OffsetX: 10, ParallaxX: 1, ParallaxY: 0.5},
+		{Opacity: 1, Visible: true, OffsetX: 9, OffsetY: 2, ParallaxX: 0.5, ParallaxY: 0.125},
+	}
+	if len(states) != len(want) {
+		t.Fatalf("got %v, expected %v", states, want)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Errorf("layer %d: got %+v, expected %+v", i, states[i], want[i])
+		}
+	}
+
+	offsets := []struct {
+		s          LayerState
+		camX, camY float64
+		x, y       float64
+	}{
+		{states[0], 64, -32, 3, 0},
+		{states[0], 164, 68, 53, 100},
+		{states[2], 164, 68, 10, 50},
+		{states[3], 0, 0, -23, 30},
+	}
+	for _, o := range offsets {
+		x, y := m.ScreenOffset(o.s, o.camX, o.camY)
+		if x != o.x || y != o.y {
+			t.Errorf("ScreenOffset(%+v, %v, %v) = %v, %v, expected %v, %v", o.s, o.camX, o.camY, x, y, o.x, o.y)
+		}
+	}
+}
+
+var testParallax = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" tiledversion="1.10.2" class="Level" orientation="orthogonal" renderorder="right-down" width="2" height="2" tilewidth="16" tileheight="16" infinite="0" parallaxoriginx="64" parallaxoriginy="-32" nextlayerid="5" nextobjectid="1">
+ <imagelayer id="1" name="Sky" class="Backdrop" tintcolor="#ff8080" locked="1" offsetx="3" parallaxx="0.5" parallaxy="0">
+  <image source="sky.png" width="32" height="32"/>
+ </imagelayer>
+ <group id="2" name="World" offsetx="10" parallaxx="0.5" parallaxy="0.5">
+  <layer id="3" name="Ground" width="2" height="2" parallaxx="2">
+   <data encoding="csv">
+1,2,
+3,1
+</data>
+  </layer>
+  <objectgroup id="4" name="Things" offsetx="-1" offsety="2" parallaxy="0.25"/>
+ </group>
+</map>
+`
@@ -0,0 +1,73 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+// Object returns the object with the given ID, or nil if there is none.
+func (m *Map) Object(id int) *Object {
+	var found *Object
+	m.Walk(func(n LayerNode, s LayerState) error {
+		g := n.ObjectGroup
+		if g == nil || found != nil {
+			return nil
+		}
+		for j := range g.Objects {
+			if g.Objects[j].ID == id {
+				found = &g.Objects[j]
+				break
+			}
+		}
+		return nil
+	})
+	return found
+}
+
+// NewObjectID returns an object ID that is not yet in use and advances NextObjectID.
+// Maps saved before Tiled tracked object IDs get one past the largest ID in use.
+func (m *Map) NewObjectID() int {
+	if m.NextObjectID <= 0 {
+		m.NextObjectID = 1
+		m.Walk(func(n LayerNode, s LayerState) error {
+			if n.ObjectGroup == nil {
+				return nil
+			}
+			for _, o := range n.ObjectGroup.Objects {
+				if o.ID >= m.NextObjectID {
+					m.NextObjectID = o.ID + 1
+				}
+			}
+			return nil
+		})
+	}
+	id := m.NextObjectID
+	m.NextObjectID++
+	return id
+}
+
+// NewLayerID returns a layer ID that is not yet in use and advances NextLayerID.
+// Maps saved before Tiled tracked layer IDs get one past the largest ID in use.
+func (m *Map) NewLayerID() int {
+	if m.NextLayerID <= 0 {
+		max := 0
+		m.Walk(func(n LayerNode, s LayerState) error {
+			var id int
+			switch {
+			case n.Layer != nil:
+				id = n.Layer.ID
+			case n.ObjectGroup != nil:
+				id = n.ObjectGroup.ID
+			case n.ImageLayer != nil:
+				id = n.ImageLayer.ID
+			case n.Group != nil:
+				id = n.Group.ID
+			}
+			if id > max {
+				max = id
+			}
+			return nil
+		})
+		m.NextLayerID = max + 1
+	}
+	id := m.NextLayerID
+	m.NextLayerID++
+	return id
+}
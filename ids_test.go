@@ -0,0 +1,74 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestObjectIDs(t *testing.T) {
+	m, err := Decode(strings.NewReader(testIDs))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	if m.NextLayerID != 4 || m.NextObjectID != 7 {
+		t.Fatalf("wrong next IDs: %d, %d", m.NextLayerID, m.NextObjectID)
+	}
+	if m.Layers[0].ID != 1 || m.Groups[0].ID != 2 || m.ObjectGroups[0].ID != 3 {
+		t.Fatalf("wrong layer IDs")
+	}
+
+	o := m.Object(5)
+	if o == nil {
+		t.Fatalf("expected object 5")
+	}
+	if o.X != 12.5 || o.Y != 40.25 || o.Width != 8.75 || o.Height != 16 || o.Rotation != 45.5 {
+		t.Fatalf("wrong object geometry: %v", o)
+	}
+	target := o.Properties[0].Value
+	if target != "6" || m.Object(6) == nil || m.Object(6).Name != "door" {
+		t.Fatalf("couldn't resolve object reference %q", target)
+	}
+	if m.Object(4) != nil {
+		t.Fatalf("expected no object 4")
+	}
+
+	if id := m.NewObjectID(); id != 7 || m.NextObjectID != 8 {
+		t.Fatalf("got new object ID %d, next %d", id, m.NextObjectID)
+	}
+	if id := m.NewLayerID(); id != 4 || m.NextLayerID != 5 {
+		t.Fatalf("got new layer ID %d, next %d", id, m.NextLayerID)
+	}
+
+	m.NextObjectID = 0
+	m.NextLayerID = 0
+	if id := m.NewObjectID(); id != 7 {
+		t.Fatalf("got new object ID %d without a counter, expected 7", id)
+	}
+	if id := m.NewLayerID(); id != 4 {
+		t.Fatalf("got new layer ID %d without a counter, expected 4", id)
+	}
+}
+
+var testIDs = `
+<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.2" orientation="orthogonal" width="1" height="1" tilewidth="16" tileheight="16" nextlayerid="4" nextobjectid="7">
+ <layer id="1" name="Ground" width="1" height="1">
+  <data encoding="csv">
+0
+</data>
+ </layer>
+ <group id="2" name="Stuff">
+  <objectgroup id="3" name="Switches">
+   <object id="5" name="lever" x="12.5" y="40.25" width="8.75" height="16" rotation="45.5">
+    <properties>
+     <property name="target" type="object" value="6"/>
+    </properties>
+   </object>
+   <object id="6" name="door" x="64" y="32" width="16" height="32"/>
+  </objectgroup>
+ </group>
+</map>
+`
@@ -0,0 +1,49 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"strings"
+)
+
+// Bytes returns the image file embedded in img's Data, decoded and decompressed.
+func (img *Image) Bytes() ([]byte, error) {
+	d := &img.Data
+	if strings.TrimSpace(d.Text) == "" {
+		return nil, errors.New("tmx: image has no embedded data")
+	}
+	if d.Encoding != "base64" {
+		return nil, fmt.Errorf("tmx: unsupported image data encoding %q", d.Encoding)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(d.Text))
+	if err != nil {
+		return nil, err
+	}
+	r, err := decompress(bytes.NewReader(raw), d.Compression)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// Decode decodes the image file embedded in img's Data with the image package,
+// which recognizes PNG, JPEG and GIF, along with any other registered formats.
+func (img *Image) Decode() (image.Image, error) {
+	b, err := img.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	i, _, err := image.Decode(bytes.NewReader(b))
+	return i, err
+}
@@ -0,0 +1,75 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedImage(t *testing.T) {
+	for _, data := range []string{testImagePng, testImageZlib} {
+		m, err := Decode(strings.NewReader(strings.Replace(testEmbeddedImage, "DATA", data, 1)))
+		if err != nil {
+			t.Fatalf("unexpected decode error: %v", err)
+		}
+
+		img := &m.Tilesets[0].Image
+		b, err := img.Bytes()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(string(b), "\x89PNG") {
+			t.Fatalf("expected PNG data, got %q", b)
+		}
+
+		i, err := img.Decode()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if i.Bounds().Dx() != 2 || i.Bounds().Dy() != 2 {
+			t.Fatalf("wrong bounds: %v", i.Bounds())
+		}
+		got := color.NRGBAModel.Convert(i.At(1, 0))
+		if got != (color.NRGBA{0, 255, 0, 255}) {
+			t.Errorf("got %v at 1, 0", got)
+		}
+		got = color.NRGBAModel.Convert(i.At(1, 1))
+		if got != (color.NRGBA{255, 255, 255, 128}) {
+			t.Errorf("got %v at 1, 1", got)
+		}
+	}
+}
+
+func TestBadEmbeddedImage(t *testing.T) {
+	for _, img := range []Image{
+		{Source: "tiles.png"},
+		{Data: Data{Encoding: "base64", Text: "~~~"}},
+		{Data: Data{Encoding: "csv", Text: "1,2,3"}},
+		{Data: Data{Encoding: "base64", Compression: "lzma", Text: "aGVsbG8="}},
+		{Data: Data{Encoding: "base64", Text: "aGVsbG8="}},
+	} {
+		if _, err := img.Decode(); err == nil {
+			t.Errorf("expected error for %+v", img)
+		}
+	}
+}
+
+var testImagePng = `<data encoding="base64">
+   iVBORw0KGgoAAAANSUhEUgAAAAIAAAACCAYAAABytg0kAAAAH0lEQVR4nAASAO3/Av8AAP8A/wD/BAEA/wD/AACBAwA/pAaDVM1KYQAAAABJRU5ErkJggg==
+  </data>`
+
+var testImageZlib = `<data encoding="base64" compression="zlib">
+   eJwAWACn/4lQTkcNChoKAAAADUlIRFIAAAACAAAAAggGAAAAcrYNJAAAAB9JREFUeJwAEgDt/wL/AAD/AP8A/wQBAP8A/wAAgQMAP6QGg1TNSmEAAAAASUVORK5CYIIDAA7PFUs=
+  </data>`
+
+var testEmbeddedImage = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.9" orientation="orthogonal" width="1" height="1" tilewidth="1" tileheight="1">
+ <tileset firstgid="1" name="embedded" tilewidth="1" tileheight="1" tilecount="4" columns="2">
+  <image format="png" width="2" height="2">
+  DATA
+  </image>
+ </tileset>
+</map>
+`
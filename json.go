@@ -0,0 +1,1233 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DecodeJSON decodes a map in Tiled's JSON format (TMJ) into the same
+// structure that Decode produces from TMX.
+func DecodeJSON(r io.Reader) (*Map, error) {
+	var jm jsonMap
+	err := json.NewDecoder(r).Decode(&jm)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := jm.toMap()
+	if err != nil {
+		return nil, err
+	}
+	err = m.buildLayers()
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DecodeJSONTileset decodes an external tileset in Tiled's JSON format (TSJ).
+func DecodeJSONTileset(r io.Reader) (*Tileset, error) {
+	var jt jsonTileset
+	err := json.NewDecoder(r).Decode(&jt)
+	if err != nil {
+		return nil, err
+	}
+	if jt.Type != "" && jt.Type != "tileset" {
+		return nil, fmt.Errorf("tmx: JSON document is a %s, not a tileset", jt.Type)
+	}
+	return jt.toTileset()
+}
+
+// DecodeJSONTemplate decodes an object template in Tiled's JSON format (TJ).
+func DecodeJSONTemplate(r io.Reader) (*Template, error) {
+	var jt jsonTemplate
+	err := json.NewDecoder(r).Decode(&jt)
+	if err != nil {
+		return nil, err
+	}
+	if jt.Type != "" && jt.Type != "template" {
+		return nil, fmt.Errorf("tmx: JSON document is a %s, not a template", jt.Type)
+	}
+
+	t := &Template{XMLName: xml.Name{Local: "template"}}
+	if jt.Tileset != nil {
+		t.Tileset, err = jt.Tileset.toTileset()
+		if err != nil {
+			return nil, err
+		}
+	}
+	o, err := jt.Object.toObject()
+	if err != nil {
+		return nil, err
+	}
+	t.Object = *o
+	return t, nil
+}
+
+// EncodeJSON writes m to w in Tiled's JSON format (TMJ). Tile layers are written
+// as arrays of GIDs or, with a base64 opts.Encoding, as compressed strings.
+// JSON has no place for embedded images, so they are left out.
+func EncodeJSON(w io.Writer, m *Map, opts EncodeOptions) error {
+	err := opts.check()
+	if err != nil {
+		return err
+	}
+	if opts.Encoding == "xml" {
+		return fmt.Errorf("tmx: cannot write xml data to JSON")
+	}
+
+	jm, err := m.toJSON(opts)
+	if err != nil {
+		return err
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", " ")
+	return e.Encode(jm)
+}
+
+// The json types mirror Tiled's JSON format. Fields that have defaults are
+// pointers, so that omitted fields can be told apart from zero values.
+
+type jsonMap struct {
+	Type            string         `json:"type"`
+	Version         jsonString     `json:"version"`
+	Orientation     string         `json:"orientation"`
+	RenderOrder     string         `json:"renderorder"`
+	Width           int            `json:"width"`
+	Height          int            `json:"height"`
+	TileWidth       int            `json:"tilewidth"`
+	TileHeight      int            `json:"tileheight"`
+	HexSideLength   int            `json:"hexsidelength,omitempty"`
+	StaggerAxis     string         `json:"staggeraxis,omitempty"`
+	StaggerIndex    string         `json:"staggerindex,omitempty"`
+	BackgroundColor string         `json:"backgroundcolor,omitempty"`
+	ParallaxOriginX float64        `json:"parallaxoriginx,omitempty"`
+	ParallaxOriginY float64        `json:"parallaxoriginy,omitempty"`
+	Class           string         `json:"class,omitempty"`
+	Infinite        bool           `json:"infinite"`
+	NextLayerID     int            `json:"nextlayerid"`
+	NextObjectID    int            `json:"nextobjectid"`
+	Properties      []jsonProperty `json:"properties,omitempty"`
+	Tilesets        []jsonTileset  `json:"tilesets"`
+	Layers          []jsonLayer    `json:"layers"`
+}
+
+// jsonString is a string that older versions of Tiled wrote as a number.
+type jsonString string
+
+func (s *jsonString) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		return json.Unmarshal(b, (*string)(s))
+	}
+	var n json.Number
+	err := json.Unmarshal(b, &n)
+	if err != nil {
+		return err
+	}
+	*s = jsonString(n)
+	return nil
+}
+
+func (jm *jsonMap) toMap() (*Map, error) {
+	if jm.Type != "" && jm.Type != "map" {
+		return nil, fmt.Errorf("tmx: JSON document is a %s, not a map", jm.Type)
+	}
+
+	m := &Map{
+		XMLName:         xml.Name{Local: "map"},
+		Version:         string(jm.Version),
+		Orientation:     jm.Orientation,
+		RenderOrder:     jm.RenderOrder,
+		Width:           jm.Width,
+		Height:          jm.Height,
+		TileWidth:       jm.TileWidth,
+		TileHeight:      jm.TileHeight,
+		HexSideLength:   jm.HexSideLength,
+		StaggerAxis:     jm.StaggerAxis,
+		StaggerIndex:    jm.StaggerIndex,
+		BackgroundColor: jm.BackgroundColor,
+		ParallaxOriginX: jm.ParallaxOriginX,
+		ParallaxOriginY: jm.ParallaxOriginY,
+		Class:           jm.Class,
+		Infinite:        jm.Infinite,
+		NextLayerID:     jm.NextLayerID,
+		NextObjectID:    jm.NextObjectID,
+	}
+	if m.RenderOrder == "" {
+		m.RenderOrder = "right-down"
+	}
+
+	var err error
+	m.Properties, err = jsonProperties(jm.Properties)
+	if err != nil {
+		return nil, err
+	}
+	for i := range jm.Tilesets {
+		ts, err := jm.Tilesets[i].toTileset()
+		if err != nil {
+			return nil, err
+		}
+		m.Tilesets = append(m.Tilesets, *ts)
+	}
+	m.LayerTree, err = jsonLayers(jm.Layers)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+type jsonTemplate struct {
+	Type    string       `json:"type"`
+	Tileset *jsonTileset `json:"tileset,omitempty"`
+	Object  jsonObject   `json:"object"`
+}
+
+type jsonProperty struct {
+	Name         string          `json:"name"`
+	Type         string          `json:"type"`
+	PropertyType string          `json:"propertytype,omitempty"`
+	Value        json.RawMessage `json:"value,omitempty"`
+}
+
+func jsonProperties(jps []jsonProperty) (Properties, error) {
+	var ps Properties
+	for _, jp := range jps {
+		p := Property{Name: jp.Name, Type: jp.Type, PropertyType: jp.PropertyType}
+		if p.Type == "string" {
+			p.Type = ""
+		}
+		err := p.setJSONValue(jp.Value)
+		if err != nil {
+			return nil, fmt.Errorf("tmx: property %s: %w", p.Name, err)
+		}
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
+
+// setJSONValue sets p's value, or its members for a class, from a JSON value.
+func (p *Property) setJSONValue(raw json.RawMessage) error {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	var v interface{}
+	err := d.Decode(&v)
+	if err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case nil:
+	case string:
+		p.Value = v
+	case bool:
+		p.Value = strconv.FormatBool(v)
+	case json.Number:
+		p.Value = v.String()
+	case map[string]interface{}:
+		// Class members carry no types of their own, so they are taken from the JSON values.
+		p.Type = "class"
+		p.Properties = nil
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			member := Property{Name: name}
+			switch mv := v[name].(type) {
+			case bool:
+				member.Type = "bool"
+			case json.Number:
+				member.Type = "int"
+				if strings.ContainsAny(mv.String(), ".eE") {
+					member.Type = "float"
+				}
+			case map[string]interface{}:
+				member.Type = "class"
+			}
+			b, err := json.Marshal(v[name])
+			if err != nil {
+				return err
+			}
+			err = member.setJSONValue(b)
+			if err != nil {
+				return err
+			}
+			p.Properties = append(p.Properties, member)
+		}
+	default:
+		return fmt.Errorf("unsupported value %s", raw)
+	}
+	return nil
+}
+
+type jsonTileset struct {
+	FirstGID         GID            `json:"firstgid,omitempty"`
+	Source           string         `json:"source,omitempty"`
+	Type             string         `json:"type,omitempty"`
+	Name             string         `json:"name,omitempty"`
+	Class            string         `json:"class,omitempty"`
+	TileWidth        int            `json:"tilewidth,omitempty"`
+	TileHeight       int            `json:"tileheight,omitempty"`
+	Spacing          int            `json:"spacing,omitempty"`
+	Margin           int            `json:"margin,omitempty"`
+	TileCount        int            `json:"tilecount,omitempty"`
+	Columns          int            `json:"columns,omitempty"`
+	Image            string         `json:"image,omitempty"`
+	ImageWidth       int            `json:"imagewidth,omitempty"`
+	ImageHeight      int            `json:"imageheight,omitempty"`
+	TransparentColor string         `json:"transparentcolor,omitempty"`
+	TileOffset       *TileOffset    `json:"tileoffset,omitempty"`
+	Properties       []jsonProperty `json:"properties,omitempty"`
+	Terrains         []jsonTerrain  `json:"terrains,omitempty"`
+	Tiles            []jsonTile     `json:"tiles,omitempty"`
+	WangSets         []jsonWangSet  `json:"wangsets,omitempty"`
+}
+
+func (jt *jsonTileset) toTileset() (*Tileset, error) {
+	ts := &Tileset{
+		FirstGID:   jt.FirstGID,
+		Source:     jt.Source,
+		Name:       jt.Name,
+		Class:      jt.Class,
+		TileWidth:  jt.TileWidth,
+		TileHeight: jt.TileHeight,
+		Spacing:    jt.Spacing,
+		Margin:     jt.Margin,
+		TileCount:  jt.TileCount,
+		Columns:    jt.Columns,
+		Image:      jsonImage(jt.Image, jt.ImageWidth, jt.ImageHeight, jt.TransparentColor),
+	}
+
+	if jt.TileOffset != nil {
+		ts.TileOffset = *jt.TileOffset
+	}
+
+	var err error
+	ts.Properties, err = jsonProperties(jt.Properties)
+	if err != nil {
+		return nil, err
+	}
+	for _, jr := range jt.Terrains {
+		t := Terrain{Name: jr.Name, Tile: jr.Tile}
+		t.Properties, err = jsonProperties(jr.Properties)
+		if err != nil {
+			return nil, err
+		}
+		ts.TerrainTypes = append(ts.TerrainTypes, t)
+	}
+	for i := range jt.Tiles {
+		t, err := jt.Tiles[i].toTile()
+		if err != nil {
+			return nil, err
+		}
+		ts.Tiles = append(ts.Tiles, *t)
+	}
+	for i := range jt.WangSets {
+		w, err := jt.WangSets[i].toWangSet()
+		if err != nil {
+			return nil, err
+		}
+		ts.WangSets = append(ts.WangSets, *w)
+	}
+	return ts, nil
+}
+
+func jsonImage(source string, width, height int, trans string) Image {
+	return Image{
+		Source: source,
+		Width:  width,
+		Height: height,
+		Trans:  strings.TrimPrefix(trans, "#"),
+	}
+}
+
+type jsonTerrain struct {
+	Name       string         `json:"name"`
+	Tile       int            `json:"tile"`
+	Properties []jsonProperty `json:"properties,omitempty"`
+}
+
+type jsonTile struct {
+	ID          int32          `json:"id"`
+	Type        string         `json:"type,omitempty"`
+	Class       string         `json:"class,omitempty"` // As written by Tiled 1.9.
+	Probability *float32       `json:"probability,omitempty"`
+	Terrain     []int          `json:"terrain,omitempty"`
+	Image       string         `json:"image,omitempty"`
+	ImageWidth  int            `json:"imagewidth,omitempty"`
+	ImageHeight int            `json:"imageheight,omitempty"`
+	X           int            `json:"x,omitempty"`
+	Y           int            `json:"y,omitempty"`
+	Width       int            `json:"width,omitempty"`
+	Height      int            `json:"height,omitempty"`
+	Properties  []jsonProperty `json:"properties,omitempty"`
+	Animation   []jsonFrame    `json:"animation,omitempty"`
+}
+
+type jsonFrame struct {
+	TileID   int32 `json:"tileid"`
+	Duration int   `json:"duration"`
+}
+
+func (jt *jsonTile) toTile() (*Tile, error) {
+	t := &Tile{
+		ID:          jt.ID,
+		Type:        jt.Type,
+		Probability: 1,
+		X:           jt.X,
+		Y:           jt.Y,
+		Width:       jt.Width,
+		Height:      jt.Height,
+		Image:       jsonImage(jt.Image, jt.ImageWidth, jt.ImageHeight, ""),
+	}
+	if t.Type == "" {
+		t.Type = jt.Class
+	}
+	if jt.Probability != nil {
+		t.Probability = *jt.Probability
+	}
+	if jt.Terrain != nil {
+		corners := make([]string, len(jt.Terrain))
+		for i, c := range jt.Terrain {
+			if c >= 0 {
+				corners[i] = strconv.Itoa(c)
+			}
+		}
+		t.Terrain = strings.Join(corners, ",")
+	}
+	for _, f := range jt.Animation {
+		t.Animation = append(t.Animation, Frame{TileID: f.TileID, Duration: f.Duration})
+	}
+
+	var err error
+	t.Properties, err = jsonProperties(jt.Properties)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+type jsonWangSet struct {
+	Name       string          `json:"name"`
+	Class      string          `json:"class,omitempty"`
+	Type       string          `json:"type"`
+	Tile       int32           `json:"tile"`
+	Properties []jsonProperty  `json:"properties,omitempty"`
+	Colors     []jsonWangColor `json:"colors"`
+	Tiles      []jsonWangTile  `json:"wangtiles"`
+}
+
+type jsonWangColor struct {
+	Name        string         `json:"name"`
+	Class       string         `json:"class,omitempty"`
+	Color       string         `json:"color"`
+	Tile        int32          `json:"tile"`
+	Probability *float64       `json:"probability"`
+	Properties  []jsonProperty `json:"properties,omitempty"`
+}
+
+type jsonWangTile struct {
+	TileID int32  `json:"tileid"`
+	WangID WangID `json:"wangid"`
+}
+
+func (jw *jsonWangSet) toWangSet() (*WangSet, error) {
+	w := &WangSet{Name: jw.Name, Class: jw.Class, Type: jw.Type, Tile: jw.Tile}
+	var err error
+	w.Properties, err = jsonProperties(jw.Properties)
+	if err != nil {
+		return nil, err
+	}
+	for _, jc := range jw.Colors {
+		c := WangColor{Name: jc.Name, Class: jc.Class, Color: jc.Color, Tile: jc.Tile, Probability: 1}
+		if jc.Probability != nil {
+			c.Probability = *jc.Probability
+		}
+		c.Properties, err = jsonProperties(jc.Properties)
+		if err != nil {
+			return nil, err
+		}
+		w.Colors = append(w.Colors, c)
+	}
+	for _, jt := range jw.Tiles {
+		w.Tiles = append(w.Tiles, WangTile{TileID: jt.TileID, WangID: jt.WangID})
+	}
+	return w, nil
+}
+
+type jsonLayer struct {
+	Type        string         `json:"type"`
+	ID          int            `json:"id"`
+	Name        string         `json:"name"`
+	Class       string         `json:"class,omitempty"`
+	Width       int            `json:"width,omitempty"`
+	Height      int            `json:"height,omitempty"`
+	Opacity     *float32       `json:"opacity"`
+	Visible     *bool          `json:"visible"`
+	X           int            `json:"x"`
+	Y           int            `json:"y"`
+	OffsetX     float64        `json:"offsetx,omitempty"`
+	OffsetY     float64        `json:"offsety,omitempty"`
+	ParallaxX   *float64       `json:"parallaxx,omitempty"`
+	ParallaxY   *float64       `json:"parallaxy,omitempty"`
+	TintColor   string         `json:"tintcolor,omitempty"`
+	Locked      bool           `json:"locked,omitempty"`
+	Properties  []jsonProperty `json:"properties,omitempty"`
+	Encoding    string         `json:"encoding,omitempty"`
+	Compression string         `json:"compression,omitempty"`
+	Data        *jsonData      `json:"data,omitempty"`
+	Chunks      []jsonChunk    `json:"chunks,omitempty"`
+	Color       string         `json:"color,omitempty"`
+	Objects     []jsonObject   `json:"objects,omitempty"`
+	Image       string         `json:"image,omitempty"`
+	ImageWidth  int            `json:"imagewidth,omitempty"`
+	ImageHeight int            `json:"imageheight,omitempty"`
+	Trans       string         `json:"transparentcolor,omitempty"`
+	Layers      []jsonLayer    `json:"layers,omitempty"`
+}
+
+// jsonData is layer data, either as an array of GIDs or as a base64 string.
+type jsonData struct {
+	GIDs []GID
+	Text string
+}
+
+func (d *jsonData) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		return json.Unmarshal(b, &d.Text)
+	}
+	return json.Unmarshal(b, &d.GIDs)
+}
+
+func (d jsonData) MarshalJSON() ([]byte, error) {
+	if d.Text != "" {
+		return json.Marshal(d.Text)
+	}
+	if d.GIDs == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(d.GIDs)
+}
+
+func (d *jsonData) gids(encoding, compression string) ([]GID, error) {
+	if d == nil {
+		return nil, nil
+	}
+	if encoding == "base64" {
+		return decodeGIDs(encoding, compression, d.Text, nil)
+	}
+	return d.GIDs, nil
+}
+
+type jsonChunk struct {
+	X      int      `json:"x"`
+	Y      int      `json:"y"`
+	Width  int      `json:"width"`
+	Height int      `json:"height"`
+	Data   jsonData `json:"data"`
+}
+
+func jsonLayers(jls []jsonLayer) ([]LayerNode, error) {
+	var nodes []LayerNode
+	for i := range jls {
+		n, err := jls[i].toNode()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+func (jl *jsonLayer) toNode() (LayerNode, error) {
+	opacity := float32(1)
+	if jl.Opacity != nil {
+		opacity = *jl.Opacity
+	}
+	visible := jl.Visible == nil || *jl.Visible
+	parallaxX, parallaxY := 1.0, 1.0
+	if jl.ParallaxX != nil {
+		parallaxX = *jl.ParallaxX
+	}
+	if jl.ParallaxY != nil {
+		parallaxY = *jl.ParallaxY
+	}
+	props, err := jsonProperties(jl.Properties)
+	if err != nil {
+		return LayerNode{}, err
+	}
+
+	switch jl.Type {
+	case "tilelayer":
+		l := &Layer{
+			ID:         jl.ID,
+			Name:       jl.Name,
+			Class:      jl.Class,
+			Width:      jl.Width,
+			Height:     jl.Height,
+			Opacity:    opacity,
+			Visible:    visible,
+			TintColor:  jl.TintColor,
+			OffsetX:    jl.OffsetX,
+			OffsetY:    jl.OffsetY,
+			ParallaxX:  parallaxX,
+			ParallaxY:  parallaxY,
+			Locked:     jl.Locked,
+			Properties: props,
+		}
+		if jl.Chunks == nil {
+			l.GIDs, err = jl.Data.gids(jl.Encoding, jl.Compression)
+			if err != nil {
+				return LayerNode{}, err
+			}
+		}
+		for _, jc := range jl.Chunks {
+			gids, err := jc.Data.gids(jl.Encoding, jl.Compression)
+			if err != nil {
+				return LayerNode{}, err
+			}
+			l.Chunks = append(l.Chunks, Chunk{X: jc.X, Y: jc.Y, Width: jc.Width, Height: jc.Height, GIDs: gids})
+		}
+		return LayerNode{Layer: l}, nil
+
+	case "objectgroup":
+		g := &ObjectGroup{
+			ID:         jl.ID,
+			Name:       jl.Name,
+			Class:      jl.Class,
+			Color:      jl.Color,
+			Opacity:    opacity,
+			Visible:    visible,
+			TintColor:  jl.TintColor,
+			OffsetX:    jl.OffsetX,
+			OffsetY:    jl.OffsetY,
+			ParallaxX:  parallaxX,
+			ParallaxY:  parallaxY,
+			Locked:     jl.Locked,
+			Properties: props,
+		}
+		for i := range jl.Objects {
+			o, err := jl.Objects[i].toObject()
+			if err != nil {
+				return LayerNode{}, err
+			}
+			g.Objects = append(g.Objects, *o)
+		}
+		return LayerNode{ObjectGroup: g}, nil
+
+	case "imagelayer":
+		return LayerNode{ImageLayer: &ImageLayer{
+			ID:         jl.ID,
+			Name:       jl.Name,
+			Class:      jl.Class,
+			Opacity:    opacity,
+			Visible:    visible,
+			TintColor:  jl.TintColor,
+			OffsetX:    jl.OffsetX,
+			OffsetY:    jl.OffsetY,
+			ParallaxX:  parallaxX,
+			ParallaxY:  parallaxY,
+			Locked:     jl.Locked,
+			Properties: props,
+			Image:      jsonImage(jl.Image, jl.ImageWidth, jl.ImageHeight, jl.Trans),
+		}}, nil
+
+	case "group":
+		g := &Group{
+			ID:         jl.ID,
+			Name:       jl.Name,
+			Class:      jl.Class,
+			Opacity:    opacity,
+			Visible:    visible,
+			TintColor:  jl.TintColor,
+			OffsetX:    jl.OffsetX,
+			OffsetY:    jl.OffsetY,
+			ParallaxX:  parallaxX,
+			ParallaxY:  parallaxY,
+			Locked:     jl.Locked,
+			Properties: props,
+		}
+		g.Layers, err = jsonLayers(jl.Layers)
+		if err != nil {
+			return LayerNode{}, err
+		}
+		return LayerNode{Group: g}, nil
+	}
+	return LayerNode{}, fmt.Errorf("tmx: unknown layer type %q", jl.Type)
+}
+
+type jsonObject struct {
+	ID         int            `json:"id"`
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Class      string         `json:"class,omitempty"`
+	X          float64        `json:"x"`
+	Y          float64        `json:"y"`
+	Width      float64        `json:"width"`
+	Height     float64        `json:"height"`
+	Rotation   float64        `json:"rotation"`
+	GID        GID            `json:"gid,omitempty"`
+	Visible    *bool          `json:"visible"`
+	Template   string         `json:"template,omitempty"`
+	Properties []jsonProperty `json:"properties,omitempty"`
+	Ellipse    bool           `json:"ellipse,omitempty"`
+	Point      bool           `json:"point,omitempty"`
+	Polygon    []Point        `json:"polygon,omitempty"`
+	Polyline   []Point        `json:"polyline,omitempty"`
+	Text       *jsonText      `json:"text,omitempty"`
+
+	// The attributes given on a template instance, as they would appear in TMX.
+	attrs []xml.Attr
+}
+
+// jsonObjectAttrs maps the JSON fields of an object to their TMX attributes.
+var jsonObjectAttrs = map[string]string{
+	"id":       "id",
+	"name":     "name",
+	"type":     "type",
+	"class":    "type",
+	"x":        "x",
+	"y":        "y",
+	"width":    "width",
+	"height":   "height",
+	"rotation": "rotation",
+	"gid":      "gid",
+	"visible":  "visible",
+	"template": "template",
+}
+
+func (jo *jsonObject) UnmarshalJSON(b []byte) error {
+	type object jsonObject
+	err := json.Unmarshal(b, (*object)(jo))
+	if err != nil {
+		return err
+	}
+	if jo.Template == "" {
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(b, &fields)
+	if err != nil {
+		return err
+	}
+	for name, raw := range fields {
+		attr, ok := jsonObjectAttrs[name]
+		if !ok {
+			continue
+		}
+		var v interface{}
+		d := json.NewDecoder(bytes.NewReader(raw))
+		d.UseNumber()
+		err = d.Decode(&v)
+		if err != nil {
+			return err
+		}
+		jo.attrs = append(jo.attrs, xml.Attr{Name: xml.Name{Local: attr}, Value: fmt.Sprint(v)})
+	}
+	sort.Slice(jo.attrs, func(i, j int) bool { return jo.attrs[i].Name.Local < jo.attrs[j].Name.Local })
+	return nil
+}
+
+func (jo *jsonObject) toObject() (*Object, error) {
+	o := &Object{
+		ID:       jo.ID,
+		Name:     jo.Name,
+		Type:     jo.Type,
+		X:        jo.X,
+		Y:        jo.Y,
+		Width:    jo.Width,
+		Height:   jo.Height,
+		Rotation: jo.Rotation,
+		GID:      jo.GID,
+		Visible:  jo.Visible == nil || *jo.Visible,
+		Template: jo.Template,
+	}
+	if o.Type == "" {
+		o.Type = jo.Class
+	}
+	if jo.Ellipse {
+		o.Ellipse = &Ellipse{}
+	}
+	if jo.Point {
+		o.Point = &PointMarker{}
+	}
+	if jo.Polygon != nil {
+		o.Polygon = Points(jo.Polygon)
+	}
+	if jo.Polyline != nil {
+		o.Polylines = Points(jo.Polyline)
+	}
+	if jo.Text != nil {
+		o.Text = jo.Text.toText()
+	}
+
+	var err error
+	o.Properties, err = jsonProperties(jo.Properties)
+	if err != nil {
+		return nil, err
+	}
+	if o.Template != "" {
+		o.instance = &instance{attrs: jo.attrs, properties: o.Properties}
+	}
+	return o, nil
+}
+
+type jsonText struct {
+	Text       string  `json:"text"`
+	FontFamily *string `json:"fontfamily,omitempty"`
+	PixelSize  *int    `json:"pixelsize,omitempty"`
+	Wrap       bool    `json:"wrap,omitempty"`
+	Color      *string `json:"color,omitempty"`
+	Bold       bool    `json:"bold,omitempty"`
+	Italic     bool    `json:"italic,omitempty"`
+	Underline  bool    `json:"underline,omitempty"`
+	Strikeout  bool    `json:"strikeout,omitempty"`
+	Kerning    *bool   `json:"kerning,omitempty"`
+	HAlign     *string `json:"halign,omitempty"`
+	VAlign     *string `json:"valign,omitempty"`
+}
+
+func (jt *jsonText) toText() *Text {
+	t := &Text{
+		FontFamily: "sans-serif",
+		PixelSize:  16,
+		Wrap:       jt.Wrap,
+		Color:      "#000000",
+		Bold:       jt.Bold,
+		Italic:     jt.Italic,
+		Underline:  jt.Underline,
+		Strikeout:  jt.Strikeout,
+		Kerning:    true,
+		HAlign:     "left",
+		VAlign:     "top",
+		Contents:   jt.Text,
+	}
+	if jt.FontFamily != nil {
+		t.FontFamily = *jt.FontFamily
+	}
+	if jt.PixelSize != nil {
+		t.PixelSize = *jt.PixelSize
+	}
+	if jt.Color != nil {
+		t.Color = *jt.Color
+	}
+	if jt.Kerning != nil {
+		t.Kerning = *jt.Kerning
+	}
+	if jt.HAlign != nil {
+		t.HAlign = *jt.HAlign
+	}
+	if jt.VAlign != nil {
+		t.VAlign = *jt.VAlign
+	}
+	return t
+}
+
+func (m *Map) toJSON(opts EncodeOptions) (*jsonMap, error) {
+	jm := &jsonMap{
+		Type:            "map",
+		Version:         jsonString(m.Version),
+		Orientation:     m.Orientation,
+		RenderOrder:     m.RenderOrder,
+		Width:           m.Width,
+		Height:          m.Height,
+		TileWidth:       m.TileWidth,
+		TileHeight:      m.TileHeight,
+		HexSideLength:   m.HexSideLength,
+		StaggerAxis:     m.StaggerAxis,
+		StaggerIndex:    m.StaggerIndex,
+		BackgroundColor: m.BackgroundColor,
+		ParallaxOriginX: m.ParallaxOriginX,
+		ParallaxOriginY: m.ParallaxOriginY,
+		Class:           m.Class,
+		Infinite:        m.Infinite,
+		NextLayerID:     m.NextLayerID,
+		NextObjectID:    m.NextObjectID,
+		Tilesets:        []jsonTileset{},
+	}
+
+	var err error
+	jm.Properties, err = m.Properties.toJSON()
+	if err != nil {
+		return nil, err
+	}
+	for i := range m.Tilesets {
+		jt, err := m.Tilesets[i].toJSON()
+		if err != nil {
+			return nil, err
+		}
+		jm.Tilesets = append(jm.Tilesets, *jt)
+	}
+	jm.Layers, err = layersToJSON(m.layerNodes(), opts)
+	if err != nil {
+		return nil, err
+	}
+	return jm, nil
+}
+
+func (ps Properties) toJSON() ([]jsonProperty, error) {
+	var jps []jsonProperty
+	for i := range ps {
+		p := &ps[i]
+		jp := jsonProperty{Name: p.Name, Type: p.Type, PropertyType: p.PropertyType}
+		if jp.Type == "" {
+			jp.Type = "string"
+		}
+		var err error
+		jp.Value, err = p.jsonValue()
+		if err != nil {
+			return nil, fmt.Errorf("tmx: property %s: %w", p.Name, err)
+		}
+		jps = append(jps, jp)
+	}
+	return jps, nil
+}
+
+// jsonValue returns p's value, or its members for a class, as a JSON value of p's type.
+func (p *Property) jsonValue() (json.RawMessage, error) {
+	var v interface{}
+	var err error
+	switch p.Type {
+	case "bool":
+		v, err = p.Bool()
+	case "int":
+		v, err = p.Int()
+	case "object":
+		v, err = p.ObjectRef()
+	case "float":
+		v, err = p.Float()
+	case "class":
+		members := make(map[string]json.RawMessage, len(p.Properties))
+		for i := range p.Properties {
+			m := &p.Properties[i]
+			members[m.Name], err = m.jsonValue()
+			if err != nil {
+				return nil, err
+			}
+		}
+		v = members
+	default:
+		v = p.Value
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func (t *Tileset) toJSON() (*jsonTileset, error) {
+	jt := &jsonTileset{FirstGID: t.FirstGID, Source: t.Source}
+	if t.Source != "" {
+		return jt, nil
+	}
+	jt.Name = t.Name
+	jt.Class = t.Class
+	jt.TileWidth = t.TileWidth
+	jt.TileHeight = t.TileHeight
+	jt.Spacing = t.Spacing
+	jt.Margin = t.Margin
+	jt.TileCount = t.TileCount
+	jt.Columns = t.Columns
+	jt.Image = t.Image.Source
+	jt.ImageWidth = t.Image.Width
+	jt.ImageHeight = t.Image.Height
+	if t.Image.Trans != "" {
+		jt.TransparentColor = "#" + t.Image.Trans
+	}
+	if t.TileOffset != (TileOffset{}) {
+		offset := t.TileOffset
+		jt.TileOffset = &offset
+	}
+
+	var err error
+	jt.Properties, err = t.Properties.toJSON()
+	if err != nil {
+		return nil, err
+	}
+	for i := range t.TerrainTypes {
+		tr := &t.TerrainTypes[i]
+		jr := jsonTerrain{Name: tr.Name, Tile: tr.Tile}
+		jr.Properties, err = tr.Properties.toJSON()
+		if err != nil {
+			return nil, err
+		}
+		jt.Terrains = append(jt.Terrains, jr)
+	}
+	for i := range t.Tiles {
+		jtile, err := t.Tiles[i].toJSON()
+		if err != nil {
+			return nil, err
+		}
+		jt.Tiles = append(jt.Tiles, *jtile)
+	}
+	for i := range t.WangSets {
+		jw, err := t.WangSets[i].toJSON()
+		if err != nil {
+			return nil, err
+		}
+		jt.WangSets = append(jt.WangSets, *jw)
+	}
+	return jt, nil
+}
+
+func (t *Tile) toJSON() (*jsonTile, error) {
+	jt := &jsonTile{
+		ID:          t.ID,
+		Type:        t.Type,
+		Image:       t.Image.Source,
+		ImageWidth:  t.Image.Width,
+		ImageHeight: t.Image.Height,
+		X:           t.X,
+		Y:           t.Y,
+		Width:       t.Width,
+		Height:      t.Height,
+	}
+	if t.Probability != 1 {
+		p := t.Probability
+		jt.Probability = &p
+	}
+	if t.Terrain != "" {
+		corners, err := t.TerrainCorners()
+		if err != nil {
+			return nil, err
+		}
+		jt.Terrain = corners[:]
+	}
+	for _, f := range t.Animation {
+		jt.Animation = append(jt.Animation, jsonFrame{TileID: f.TileID, Duration: f.Duration})
+	}
+
+	var err error
+	jt.Properties, err = t.Properties.toJSON()
+	if err != nil {
+		return nil, err
+	}
+	return jt, nil
+}
+
+func (w *WangSet) toJSON() (*jsonWangSet, error) {
+	jw := &jsonWangSet{
+		Name:   w.Name,
+		Class:  w.Class,
+		Type:   w.Type,
+		Tile:   w.Tile,
+		Colors: []jsonWangColor{},
+		Tiles:  []jsonWangTile{},
+	}
+	var err error
+	jw.Properties, err = w.Properties.toJSON()
+	if err != nil {
+		return nil, err
+	}
+	for i := range w.Colors {
+		c := &w.Colors[i]
+		p := c.Probability
+		jc := jsonWangColor{Name: c.Name, Class: c.Class, Color: c.Color, Tile: c.Tile, Probability: &p}
+		jc.Properties, err = c.Properties.toJSON()
+		if err != nil {
+			return nil, err
+		}
+		jw.Colors = append(jw.Colors, jc)
+	}
+	for _, t := range w.Tiles {
+		jw.Tiles = append(jw.Tiles, jsonWangTile{TileID: t.TileID, WangID: t.WangID})
+	}
+	return jw, nil
+}
+
+func layersToJSON(nodes []LayerNode, opts EncodeOptions) ([]jsonLayer, error) {
+	jls := []jsonLayer{}
+	for _, n := range nodes {
+		jl, err := n.toJSON(opts)
+		if err != nil {
+			return nil, err
+		}
+		jls = append(jls, *jl)
+	}
+	return jls, nil
+}
+
+func (n LayerNode) toJSON(opts EncodeOptions) (*jsonLayer, error) {
+	var jl jsonLayer
+	var props Properties
+	var opacity float32
+	var visible bool
+	var parallaxX, parallaxY float64
+	var err error
+
+	switch {
+	case n.Layer != nil:
+		l := n.Layer
+		jl = jsonLayer{Type: "tilelayer", ID: l.ID, Name: l.Name, Class: l.Class, Width: l.Width, Height: l.Height}
+		props, opacity, visible = l.Properties, l.Opacity, l.Visible
+		jl.TintColor, jl.OffsetX, jl.OffsetY, jl.Locked = l.TintColor, l.OffsetX, l.OffsetY, l.Locked
+		parallaxX, parallaxY = l.ParallaxX, l.ParallaxY
+		if opts.Encoding == "base64" {
+			jl.Encoding = opts.Encoding
+			jl.Compression = opts.Compression
+		}
+		if l.Chunks == nil {
+			jl.Data, err = jsonDataOf(l.GIDs, opts)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for i := range l.Chunks {
+			c := &l.Chunks[i]
+			d, err := jsonDataOf(c.GIDs, opts)
+			if err != nil {
+				return nil, err
+			}
+			jl.Chunks = append(jl.Chunks, jsonChunk{X: c.X, Y: c.Y, Width: c.Width, Height: c.Height, Data: *d})
+		}
+
+	case n.ObjectGroup != nil:
+		g := n.ObjectGroup
+		jl = jsonLayer{Type: "objectgroup", ID: g.ID, Name: g.Name, Class: g.Class, Color: g.Color, Objects: []jsonObject{}}
+		props, opacity, visible = g.Properties, g.Opacity, g.Visible
+		jl.TintColor, jl.OffsetX, jl.OffsetY, jl.Locked = g.TintColor, g.OffsetX, g.OffsetY, g.Locked
+		parallaxX, parallaxY = g.ParallaxX, g.ParallaxY
+		for i := range g.Objects {
+			jo, err := g.Objects[i].toJSON()
+			if err != nil {
+				return nil, err
+			}
+			jl.Objects = append(jl.Objects, *jo)
+		}
+
+	case n.ImageLayer != nil:
+		l := n.ImageLayer
+		jl = jsonLayer{
+			Type:        "imagelayer",
+			ID:          l.ID,
+			Name:        l.Name,
+			Class:       l.Class,
+			Image:       l.Image.Source,
+			ImageWidth:  l.Image.Width,
+			ImageHeight: l.Image.Height,
+		}
+		if l.Image.Trans != "" {
+			jl.Trans = "#" + l.Image.Trans
+		}
+		props, opacity, visible = l.Properties, l.Opacity, l.Visible
+		jl.TintColor, jl.OffsetX, jl.OffsetY, jl.Locked = l.TintColor, l.OffsetX, l.OffsetY, l.Locked
+		parallaxX, parallaxY = l.ParallaxX, l.ParallaxY
+
+	case n.Group != nil:
+		g := n.Group
+		jl = jsonLayer{Type: "group", ID: g.ID, Name: g.Name, Class: g.Class}
+		props, opacity, visible = g.Properties, g.Opacity, g.Visible
+		jl.TintColor, jl.OffsetX, jl.OffsetY, jl.Locked = g.TintColor, g.OffsetX, g.OffsetY, g.Locked
+		parallaxX, parallaxY = g.ParallaxX, g.ParallaxY
+		jl.Layers, err = layersToJSON(g.Layers, opts)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("tmx: empty layer node")
+	}
+
+	jl.Opacity = &opacity
+	jl.Visible = &visible
+	// Tiled leaves out parallax factors of 1.
+	if parallaxX != 1 {
+		jl.ParallaxX = &parallaxX
+	}
+	if parallaxY != 1 {
+		jl.ParallaxY = &parallaxY
+	}
+	jl.Properties, err = props.toJSON()
+	if err != nil {
+		return nil, err
+	}
+	return &jl, nil
+}
+
+// jsonDataOf returns gids as an array, or as a base64 string for a base64 opts.Encoding.
+func jsonDataOf(gids []GID, opts EncodeOptions) (*jsonData, error) {
+	if opts.Encoding != "base64" {
+		return &jsonData{GIDs: gids}, nil
+	}
+	text, err := encodeGIDs(gids, 0, opts.Encoding, opts.Compression)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonData{Text: text}, nil
+}
+
+func (o *Object) toJSON() (*jsonObject, error) {
+	visible := o.Visible
+	jo := &jsonObject{
+		ID:       o.ID,
+		Name:     o.Name,
+		Type:     o.Type,
+		X:        o.X,
+		Y:        o.Y,
+		Width:    o.Width,
+		Height:   o.Height,
+		Rotation: o.Rotation,
+		GID:      o.GID,
+		Visible:  &visible,
+		Template: o.Template,
+		Ellipse:  o.Ellipse != nil,
+		Point:    o.Point != nil,
+		Polygon:  o.Polygon,
+		Polyline: o.Polylines,
+	}
+	if o.Text != nil {
+		jo.Text = o.Text.toJSON()
+	}
+
+	var err error
+	jo.Properties, err = o.Properties.toJSON()
+	if err != nil {
+		return nil, err
+	}
+	return jo, nil
+}
+
+// toJSON returns t with the attributes that have their default values left out.
+func (t *Text) toJSON() *jsonText {
+	jt := &jsonText{
+		Text:      t.Contents,
+		Wrap:      t.Wrap,
+		Bold:      t.Bold,
+		Italic:    t.Italic,
+		Underline: t.Underline,
+		Strikeout: t.Strikeout,
+	}
+	if t.FontFamily != "sans-serif" {
+		jt.FontFamily = &t.FontFamily
+	}
+	if t.PixelSize != 16 {
+		jt.PixelSize = &t.PixelSize
+	}
+	if t.Color != "#000000" {
+		jt.Color = &t.Color
+	}
+	if !t.Kerning {
+		jt.Kerning = &t.Kerning
+	}
+	if t.HAlign != "left" {
+		jt.HAlign = &t.HAlign
+	}
+	if t.VAlign != "top" {
+		jt.VAlign = &t.VAlign
+	}
+	return jt
+}
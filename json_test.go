@@ -0,0 +1,513 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/eaburns/eq"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	want, err := Decode(strings.NewReader(testCsv))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	for i, data := range []string{testJSONArray, testJSONBase64} {
+		m, err := DecodeJSON(strings.NewReader(strings.Replace(testJSON, "DATA", data, 1)))
+		if err != nil {
+			t.Fatalf("unexpected decode error for %d: %v", i, err)
+		}
+		if !eq.Deep(m, want) {
+			t.Fatalf("unequal %d:\n%v\n------\n%v", i, m, want)
+		}
+	}
+}
+
+func TestDecodeJSONFeatures(t *testing.T) {
+	m, err := DecodeJSON(strings.NewReader(testJSONFeatures))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	want, err := Decode(strings.NewReader(testTMXFeatures))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if !eq.Deep(m, want) {
+		t.Fatalf("unequal:\n%+v\n------\n%+v", m, want)
+	}
+}
+
+func TestBadDecodeJSON(t *testing.T) {
+	for i, s := range []string{
+		`{"type": "map", "layers": [{"type": "tilelayer", "data": "AAAA~", "encoding": "base64"}]}`,
+		`{"type": "map", "layers": [{"type": "tilelayer", "data": [1, -2]}]}`,
+		`{"type": "map", "layers": [{"type": "sandwich"}]}`,
+		`{"type": "tileset"}`,
+		`{"type": "map", "properties": [{"name": "x", "value": [1]}]}`,
+		`{"type": "map"`,
+	} {
+		_, err := DecodeJSON(strings.NewReader(s))
+		if err == nil {
+			t.Fatalf("expected decode error for %d", i)
+		}
+	}
+}
+
+func TestDecodeFileJSON(t *testing.T) {
+	fsys := fstest.MapFS{
+		"maps/land.tmj":     {Data: []byte("\n  " + strings.Replace(testJSON, "DATA", testJSONArray, 1))},
+		"maps/land.tmx":     {Data: []byte(testCsv)},
+		"tilesets/land.tsx": {Data: []byte(testTsx)},
+	}
+
+	m, err := DecodeFile("maps/land.tmj", FS(fsys))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	want, err := DecodeFile("maps/land.tmx", FS(fsys))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if !eq.Deep(m, want) {
+		t.Fatalf("unequal:\n%v\n------\n%v", m, want)
+	}
+}
+
+var testJSON = `{
+ "type": "map",
+ "version": "1.0",
+ "orientation": "orthogonal",
+ "width": 10,
+ "height": 10,
+ "tilewidth": 16,
+ "tileheight": 16,
+ "infinite": false,
+ "tilesets": [
+  {"firstgid": 1, "name": "land", "tilewidth": 16, "tileheight": 16, "image": "tiles.png", "imagewidth": 48, "imageheight": 16}
+ ],
+ "layers": [
+  {
+   "type": "tilelayer",
+   "name": "Foreground",
+   "width": 10,
+   "height": 10,
+   "opacity": 1,
+   "visible": true,
+   DATA
+  },
+  {
+   "type": "objectgroup",
+   "name": "Mountains",
+   "opacity": 1,
+   "visible": true,
+   "objects": [
+    {"type": "mountain", "x": 48, "y": 48, "width": 16, "height": 16, "rotation": 0, "visible": true},
+    {"type": "mountain", "x": 64, "y": 32, "width": 16, "height": 16, "rotation": 0, "visible": true},
+    {"type": "mountain", "x": 80, "y": 32, "width": 16, "height": 16, "rotation": 0, "visible": true},
+    {"type": "mountain", "x": 64, "y": 48, "width": 16, "height": 16, "rotation": 0, "visible": true},
+    {"type": "mountain", "x": 96, "y": 48, "width": 16, "height": 16, "rotation": 0, "visible": true},
+    {"type": "mountain", "x": 80, "y": 48, "width": 16, "height": 16, "rotation": 0, "visible": true},
+    {"type": "mountain", "x": 64, "y": 64, "width": 16, "height": 16, "rotation": 0, "visible": true},
+    {"type": "mountain", "x": 80, "y": 64, "width": 16, "height": 16, "rotation": 0, "visible": true},
+    {"type": "mountain", "x": 96, "y": 64, "width": 16, "height": 16, "rotation": 0, "visible": true},
+    {"type": "mountain", "x": 80, "y": 80, "width": 16, "height": 16, "rotation": 0, "visible": true},
+    {"type": "mountain", "x": 96, "y": 80, "width": 16, "height": 16, "rotation": 0, "visible": true}
+   ]
+  }
+ ]
+}
+`
+
+var testJSONArray = `"data": [
+   3, 3, 1, 1, 1, 1, 1, 1, 1, 3,
+   3, 1, 1, 3, 3, 3, 3, 1, 1, 1,
+   1, 1, 3, 3, 2, 2, 3, 3, 1, 1,
+   1, 1, 3, 2, 2, 2, 2, 3, 1, 1,
+   1, 1, 3, 3, 2, 2, 2, 3, 1, 1,
+   1, 1, 3, 3, 3, 2, 2, 3, 1, 1,
+   1, 1, 3, 3, 3, 3, 3, 3, 3, 1,
+   3, 1, 1, 3, 3, 3, 3, 3, 3, 1,
+   3, 1, 1, 1, 1, 1, 3, 3, 1, 1,
+   3, 3, 1, 1, 1, 1, 1, 1, 1, 3
+   ]`
+
+var testJSONBase64 = `"encoding": "base64",
+   "compression": "zlib",
+   "data": "eJxjZmBgYAZiRjyYGU0NMxrGp4cJinGpRVaDrJaQeYTUoduNTx02/+DzLy51xIQZoXAGAJlEAMI="`
+
+var testJSONFeatures = `{
+ "type": "map",
+ "version": 1.2,
+ "orientation": "hexagonal",
+ "renderorder": "left-up",
+ "width": 4,
+ "height": 2,
+ "tilewidth": 14,
+ "tileheight": 12,
+ "hexsidelength": 6,
+ "staggeraxis": "y",
+ "staggerindex": "even",
+ "backgroundcolor": "#102030",
+ "infinite": true,
+ "nextlayerid": 6,
+ "nextobjectid": 5,
+ "properties": [
+  {"name": "title", "type": "string", "value": "Caves"},
+  {"name": "dark", "type": "bool", "value": true},
+  {"name": "depth", "type": "int", "value": 3},
+  {"name": "gravity", "type": "float", "value": 9.5},
+  {"name": "ambient", "type": "color", "value": "#ff102030"},
+  {"name": "door", "type": "class", "propertytype": "Door", "value": {"locked": true, "code": 1234, "label": "vault", "size": 1.5}}
+ ],
+ "tilesets": [
+  {"firstgid": 1, "source": "land.tsx"},
+  {
+   "firstgid": 5,
+   "name": "water",
+   "tilewidth": 14,
+   "tileheight": 12,
+   "tilecount": 4,
+   "columns": 4,
+   "spacing": 1,
+   "margin": 2,
+   "image": "water.png",
+   "imagewidth": 64,
+   "imageheight": 16,
+   "transparentcolor": "#ff00ff",
+   "tileoffset": {"x": 1, "y": -2},
+   "terrains": [{"name": "shallow", "tile": 0}],
+   "tiles": [
+    {"id": 0, "terrain": [0, -1, 0, 0], "animation": [{"tileid": 0, "duration": 100}, {"tileid": 1, "duration": 150}]},
+    {"id": 2, "probability": 0.25, "properties": [{"name": "deep", "type": "bool", "value": true}]}
+   ],
+   "wangsets": [
+    {"name": "Water", "type": "corner", "tile": -1,
+     "colors": [{"name": "Sea", "color": "#0000ff", "tile": 2, "probability": 0.5}],
+     "wangtiles": [{"tileid": 2, "wangid": [0, 1, 0, 1, 0, 1, 0, 1]}]}
+   ]
+  }
+ ],
+ "layers": [
+  {
+   "type": "group", "id": 1, "name": "World", "offsetx": 4, "offsety": -2, "opacity": 0.5, "visible": true,
+   "layers": [
+    {
+     "type": "tilelayer", "id": 2, "name": "Ground", "width": 4, "height": 2, "opacity": 1, "visible": true,
+     "encoding": "base64",
+     "chunks": [
+      {"x": -2, "y": -2, "width": 2, "height": 2, "data": "AQAAAAIAAAADAAAABAAAAA=="},
+      {"x": 0, "y": -2, "width": 2, "height": 2, "data": "BQAAAAAAAAAAAAAABgAAAA=="}
+     ]
+    }
+   ]
+  },
+  {
+   "type": "objectgroup", "id": 3, "name": "Things", "color": "#a0a0a4", "opacity": 1, "visible": false,
+   "objects": [
+    {"id": 1, "name": "pond", "class": "water", "x": 1.5, "y": 2, "width": 0, "height": 0, "rotation": 0, "visible": true,
+     "polygon": [{"x": 0, "y": 0}, {"x": 8, "y": 0.5}, {"x": 4, "y": 6}]},
+    {"id": 2, "x": 0, "y": 0, "width": 0, "height": 0, "rotation": 0, "visible": true, "polyline": [{"x": 0, "y": 0}, {"x": -3, "y": 3}]},
+    {"id": 3, "name": "spawn", "x": 7, "y": 8, "width": 0, "height": 0, "rotation": 0, "visible": true, "point": true},
+    {"id": 4, "name": "sign", "x": 0, "y": 0, "width": 40, "height": 12, "rotation": 90, "visible": false,
+     "text": {"text": "Hi", "wrap": true, "pixelsize": 8, "kerning": false, "halign": "right"}},
+    {"id": 5, "x": 2, "y": 2, "width": 4, "height": 4, "rotation": 0, "gid": 2147483653, "visible": true, "ellipse": true,
+     "properties": [{"name": "target", "type": "object", "value": 3}]}
+   ]
+  },
+  {
+   "type": "imagelayer", "id": 4, "name": "Sky", "opacity": 0.75, "visible": true,
+   "image": "sky.png", "imagewidth": 64, "imageheight": 32, "transparentcolor": "#000000"
+  }
+ ]
+}
+`
+
+var testTMXFeatures = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.2" orientation="hexagonal" renderorder="left-up" width="4" height="2" tilewidth="14" tileheight="12" infinite="1" hexsidelength="6" staggeraxis="y" staggerindex="even" backgroundcolor="#102030" nextlayerid="6" nextobjectid="5">
+ <properties>
+  <property name="title" value="Caves"/>
+  <property name="dark" type="bool" value="true"/>
+  <property name="depth" type="int" value="3"/>
+  <property name="gravity" type="float" value="9.5"/>
+  <property name="ambient" type="color" value="#ff102030"/>
+  <property name="door" type="class" propertytype="Door">
+   <properties>
+    <property name="code" type="int" value="1234"/>
+    <property name="label" value="vault"/>
+    <property name="locked" type="bool" value="true"/>
+    <property name="size" type="float" value="1.5"/>
+   </properties>
+  </property>
+ </properties>
+ <tileset firstgid="1" source="land.tsx"/>
+ <tileset firstgid="5" name="water" tilewidth="14" tileheight="12" spacing="1" margin="2" tilecount="4" columns="4">
+  <tileoffset x="1" y="-2"/>
+  <image source="water.png" trans="ff00ff" width="64" height="16"/>
+  <terraintypes>
+   <terrain name="shallow" tile="0"/>
+  </terraintypes>
+  <tile id="0" terrain="0,,0,0">
+   <animation>
+    <frame tileid="0" duration="100"/>
+    <frame tileid="1" duration="150"/>
+   </animation>
+  </tile>
+  <tile id="2" probability="0.25">
+   <properties>
+    <property name="deep" type="bool" value="true"/>
+   </properties>
+  </tile>
+  <wangsets>
+   <wangset name="Water" type="corner" tile="-1">
+    <wangcolor name="Sea" color="#0000ff" tile="2" probability="0.5"/>
+    <wangtile tileid="2" wangid="0,1,0,1,0,1,0,1"/>
+   </wangset>
+  </wangsets>
+ </tileset>
+ <group id="1" name="World" offsetx="4" offsety="-2" opacity="0.5">
+  <layer id="2" name="Ground" width="4" height="2">
+   <data encoding="csv">
+    <chunk x="-2" y="-2" width="2" height="2">
+1,2,
+3,4
+</chunk>
+    <chunk x="0" y="-2" width="2" height="2">
+5,0,
+0,6
+</chunk>
+   </data>
+  </layer>
+ </group>
+ <objectgroup id="3" name="Things" color="#a0a0a4" visible="0">
+  <object id="1" name="pond" type="water" x="1.5" y="2">
+   <polygon points="0,0 8,0.5 4,6"/>
+  </object>
+  <object id="2" x="0" y="0">
+   <polyline points="0,0 -3,3"/>
+  </object>
+  <object id="3" name="spawn" x="7" y="8">
+   <point/>
+  </object>
+  <object id="4" name="sign" x="0" y="0" width="40" height="12" rotation="90" visible="0">
+   <text pixelsize="8" wrap="1" kerning="0" halign="right">Hi</text>
+  </object>
+  <object id="5" gid="2147483653" x="2" y="2" width="4" height="4">
+   <properties>
+    <property name="target" type="object" value="3"/>
+   </properties>
+   <ellipse/>
+  </object>
+ </objectgroup>
+ <imagelayer id="4" name="Sky" opacity="0.75">
+  <image source="sky.png" trans="000000" width="64" height="32"/>
+ </imagelayer>
+</map>
+`
+
+func TestMixedFormats(t *testing.T) {
+	fsys := fstest.MapFS{
+		"maps/level.tmx":         {Data: []byte(strings.Replace(testTemplateMap, "land.tsx", "land.tsj", 1))},
+		"maps/level.tmj":         {Data: []byte(testJSONTemplateMap)},
+		"tilesets/land.tsx":      {Data: []byte(testTsx)},
+		"tilesets/land.tsj":      {Data: []byte(testTsj)},
+		"tilesets/monsters.tsx":  {Data: []byte(testMonstersTsx)},
+		"templates/enemy.tx":     {Data: []byte(testEnemyTx)},
+		"templates/sign.tx":      {Data: []byte(testSignTj)},
+		"templates/boss/boss.tx": {Data: []byte(testBossTj)},
+	}
+
+	want, err := DecodeFile("maps/level.tmx", FS(fstest.MapFS{
+		"maps/level.tmx":         fsys["maps/level.tmx"],
+		"tilesets/land.tsj":      {Data: []byte(testTsx)},
+		"tilesets/monsters.tsx":  {Data: []byte(testMonstersTsx)},
+		"templates/enemy.tx":     {Data: []byte(strings.Replace(testEnemyTx, "land.tsx", "land.tsj", 1))},
+		"templates/sign.tx":      {Data: []byte(testSignTx)},
+		"templates/boss/boss.tx": {Data: []byte(testBossTx)},
+	}))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	fsys["templates/enemy.tx"] = &fstest.MapFile{Data: []byte(strings.Replace(testEnemyTx, "land.tsx", "land.tsj", 1))}
+	m, err := DecodeFile("maps/level.tmx", FS(fsys))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if !eq.Deep(m, want) {
+		t.Fatalf("unequal TMX map:\n%+v\n------\n%+v", m, want)
+	}
+
+	m, err = DecodeFile("maps/level.tmj", FS(fsys))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if !eq.Deep(m.Tilesets, want.Tilesets) {
+		t.Fatalf("unequal tilesets:\n%+v\n------\n%+v", m.Tilesets, want.Tilesets)
+	}
+	objs, wantObjs := withoutInstances(m.ObjectGroups[0].Objects), withoutInstances(want.ObjectGroups[0].Objects)
+	if !eq.Deep(objs, wantObjs) {
+		t.Fatalf("unequal objects:\n%+v\n------\n%+v", objs, wantObjs)
+	}
+}
+
+func TestBadJSONTileset(t *testing.T) {
+	if _, err := DecodeJSONTileset(strings.NewReader(`{"type": "map"}`)); err == nil {
+		t.Errorf("expected error for a map as a tileset")
+	}
+	if _, err := DecodeJSONTemplate(strings.NewReader(`{"type": "tileset"}`)); err == nil {
+		t.Errorf("expected error for a tileset as a template")
+	}
+}
+
+var testTsj = `{
+ "type": "tileset",
+ "name": "land",
+ "tilewidth": 16,
+ "tileheight": 16,
+ "image": "tiles.png",
+ "imagewidth": 48,
+ "imageheight": 16
+}
+`
+
+var testSignTj = `{
+ "type": "template",
+ "object": {"name": "sign", "x": 0, "y": 0, "width": 64, "height": 16, "rotation": 0, "visible": true, "text": {"text": "Welcome"}}
+}
+`
+
+var testBossTj = `{
+ "type": "template",
+ "tileset": {"firstgid": 1, "source": "../../tilesets/monsters.tsx"},
+ "object": {"name": "boss", "type": "enemy", "gid": 2, "width": 48, "height": 48, "rotation": 0, "visible": true}
+}
+`
+
+var testJSONTemplateMap = `{
+ "type": "map",
+ "version": "1.10",
+ "orientation": "orthogonal",
+ "width": 10,
+ "height": 10,
+ "tilewidth": 16,
+ "tileheight": 16,
+ "nextobjectid": 5,
+ "tilesets": [{"firstgid": 1, "source": "../tilesets/land.tsj"}],
+ "layers": [
+  {
+   "type": "objectgroup", "id": 1, "name": "Actors", "opacity": 1, "visible": true,
+   "objects": [
+    {"id": 1, "template": "../templates/enemy.tx", "x": 32, "y": 48},
+    {"id": 2, "template": "../templates/enemy.tx", "name": "captain", "gid": 2147483651, "x": 64, "y": 48, "width": 32, "height": 32,
+     "properties": [{"name": "hp", "type": "string", "value": "20"}, {"name": "armor", "type": "string", "value": "3"}]},
+    {"id": 3, "template": "../templates/sign.tx", "x": 8, "y": 8, "text": {"text": "Keep out"}},
+    {"id": 4, "template": "../templates/boss/boss.tx", "x": 100, "y": 100}
+   ]
+  }
+ ]
+}
+`
+
+func TestEncodeJSON(t *testing.T) {
+	docs := []string{
+		testCsv, testTMXFeatures, testGroups, testParallax, testShapes,
+		testAnimation, testCollection, testHexagonal, testWang,
+		strings.Replace(testPoly, "POINTS", "0,0 32,0 32,32.5", 1),
+		strings.Replace(testInfinite, "DATA", testChunksCsv, 1),
+		strings.NewReplacer(`name="land"`, `name="land" class="Biome"`, "<wangsets>", `<tile id="2" type="Chest"/><wangsets>`).Replace(testWang),
+	}
+	opts := []EncodeOptions{
+		{},
+		{Encoding: "base64"},
+		{Encoding: "base64", Compression: "zlib"},
+		{Encoding: "base64", Compression: "zstd"},
+	}
+
+	for i, doc := range docs {
+		want, err := Decode(strings.NewReader(doc))
+		if err != nil {
+			t.Fatalf("unexpected decode error for %d: %v", i, err)
+		}
+		for _, o := range opts {
+			var b bytes.Buffer
+			err = EncodeJSON(&b, want, o)
+			if err != nil {
+				t.Fatalf("unexpected encode error for %d with %+v: %v", i, o, err)
+			}
+			m, err := DecodeJSON(&b)
+			if err != nil {
+				t.Fatalf("unexpected decode error for %d with %+v: %v", i, o, err)
+			}
+			if !eq.Deep(m, want) {
+				t.Fatalf("unequal %d with %+v:\n%+v\n------\n%+v", i, o, m, want)
+			}
+		}
+	}
+}
+
+func TestEncodeJSONProperties(t *testing.T) {
+	m, err := Decode(strings.NewReader(testProperties))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	var b bytes.Buffer
+	err = EncodeJSON(&b, m, EncodeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+
+	var doc struct {
+		Properties []struct {
+			Name  string
+			Type  string
+			Value interface{}
+		}
+	}
+	err = json.Unmarshal(b.Bytes(), &doc)
+	if err != nil {
+		t.Fatalf("unexpected JSON error: %v", err)
+	}
+	want := map[string]interface{}{
+		"solid": true,
+		"hp":    -12.0,
+		"speed": 2.5,
+		"tint":  "#80112233",
+		"exit":  7.0,
+		"intro": "Once upon a time\nthere was a map.",
+		"door": map[string]interface{}{
+			"locked": true,
+			"key":    map[string]interface{}{"color": "red"},
+		},
+	}
+	for _, p := range doc.Properties {
+		if p.Type == "" {
+			t.Errorf("expected a type for %s", p.Name)
+		}
+		if w, ok := want[p.Name]; ok && !eq.Deep(p.Value, w) {
+			t.Errorf("unexpected value for %s: %#v, want %#v", p.Name, p.Value, w)
+		}
+	}
+}
+
+func TestBadEncodeJSON(t *testing.T) {
+	m, err := Decode(strings.NewReader(testCsv))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	err = EncodeJSON(new(bytes.Buffer), m, EncodeOptions{Encoding: "xml"})
+	if err == nil {
+		t.Errorf("expected error for xml encoding")
+	}
+
+	m.Properties = Properties{{Name: "hp", Type: "int", Value: "lots"}}
+	err = EncodeJSON(new(bytes.Buffer), m, EncodeOptions{})
+	if err == nil {
+		t.Errorf("expected error for a malformed int property")
+	}
+}
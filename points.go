@@ -0,0 +1,66 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Points are the vertices of a polygon or polyline, relative to the object's position.
+type Points []Point
+
+// UnmarshalXML decodes the points attribute of a <polygon> or <polyline> element.
+func (ps *Points) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, a := range start.Attr {
+		if a.Name.Local != "points" {
+			continue
+		}
+		var err error
+		*ps, err = parsePoints(a.Value)
+		if err != nil {
+			return err
+		}
+	}
+	if *ps == nil {
+		*ps = Points{}
+	}
+	return d.Skip()
+}
+
+// String returns ps in the form "x1,y1 x2,y2 ...".
+func (ps Points) String() string {
+	parts := make([]string, len(ps))
+	for i, p := range ps {
+		parts[i] = strconv.FormatFloat(p.X, 'g', -1, 64) + "," + strconv.FormatFloat(p.Y, 'g', -1, 64)
+	}
+	return strings.Join(parts, " ")
+}
+
+// parsePoints parses a list of points in the form "x1,y1 x2,y2 ...".
+func parsePoints(s string) (Points, error) {
+	ps := Points{}
+	for _, f := range strings.Fields(s) {
+		xy := strings.Split(f, ",")
+		if len(xy) != 2 {
+			return nil, fmt.Errorf("tmx: malformed point %q", f)
+		}
+		x, err := strconv.ParseFloat(xy[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("tmx: malformed point %q: %w", f, err)
+		}
+		y, err := strconv.ParseFloat(xy[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("tmx: malformed point %q: %w", f, err)
+		}
+		ps = append(ps, Point{x, y})
+	}
+	return ps, nil
+}
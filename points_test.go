@@ -0,0 +1,54 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eaburns/eq"
+)
+
+func TestPoints(t *testing.T) {
+	m, err := Decode(strings.NewReader(strings.Replace(testPoly, "POINTS", "0,0 32,0 32,32.5", 1)))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	objs := m.ObjectGroups[0].Objects
+	want := Points{{0, 0}, {32, 0}, {32, 32.5}}
+	if !eq.Deep(objs[0].Polygon, want) || objs[0].Polylines != nil {
+		t.Fatalf("wrong polygon: %v, %v", objs[0].Polygon, objs[0].Polylines)
+	}
+	want = Points{{0, 0}, {-16, 8}, {-16.25, 24}}
+	if !eq.Deep(objs[1].Polylines, want) || objs[1].Polygon != nil {
+		t.Fatalf("wrong polyline: %v, %v", objs[1].Polylines, objs[1].Polygon)
+	}
+	if objs[2].Polygon != nil || objs[2].Polylines != nil {
+		t.Fatalf("expected no points for a rectangle")
+	}
+}
+
+func TestBadPoints(t *testing.T) {
+	for _, s := range []string{"0,0 32", "0,0 32,x", "y,0", "0,0,0"} {
+		_, err := Decode(strings.NewReader(strings.Replace(testPoly, "POINTS", s, 1)))
+		if err == nil {
+			t.Fatalf("expected decode error for %q", s)
+		}
+	}
+}
+
+var testPoly = `
+<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.2" orientation="orthogonal" width="1" height="1" tilewidth="16" tileheight="16">
+ <objectgroup id="1" name="Paths">
+  <object id="1" x="16" y="16">
+   <polygon points="POINTS"/>
+  </object>
+  <object id="2" x="64" y="16">
+   <polyline points="0,0 -16,8 -16.25,24"/>
+  </object>
+  <object id="3" x="0" y="0" width="8" height="8"/>
+ </objectgroup>
+</map>
+`
@@ -0,0 +1,293 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A Project holds the custom property types of a Tiled project (.tiled-project) file.
+type Project struct {
+	PropertyTypes []PropertyType
+}
+
+// A PropertyType is a custom class or enum type.
+type PropertyType struct {
+	ID   int
+	Name string
+	Type string // One of class or enum.
+
+	// The members of a class, with their default values.
+	Members Properties
+
+	// What a class may be used as, such as property, map, layer, object or tile.
+	UseAs []string
+
+	// The values of an enum, and whether they are stored as
+	// their names (string) or their indexes (int).
+	Values      []string
+	StorageType string
+
+	// Whether an enum's value is a set of flags rather than a single value.
+	ValuesAsFlags bool
+}
+
+type jsonProject struct {
+	PropertyTypes []jsonPropertyType `json:"propertyTypes"`
+}
+
+type jsonPropertyType struct {
+	ID            int            `json:"id"`
+	Name          string         `json:"name"`
+	Type          string         `json:"type"`
+	Members       []jsonProperty `json:"members"`
+	UseAs         []string       `json:"useAs"`
+	Values        []string       `json:"values"`
+	StorageType   string         `json:"storageType"`
+	ValuesAsFlags bool           `json:"valuesAsFlags"`
+}
+
+// DecodeProject decodes a Tiled project file.
+func DecodeProject(r io.Reader) (*Project, error) {
+	var jp jsonProject
+	err := json.NewDecoder(r).Decode(&jp)
+	if err != nil {
+		return nil, err
+	}
+
+	p := new(Project)
+	for _, jt := range jp.PropertyTypes {
+		t := PropertyType{
+			ID:            jt.ID,
+			Name:          jt.Name,
+			Type:          jt.Type,
+			UseAs:         jt.UseAs,
+			Values:        jt.Values,
+			StorageType:   jt.StorageType,
+			ValuesAsFlags: jt.ValuesAsFlags,
+		}
+		t.Members, err = jsonProperties(jt.Members)
+		if err != nil {
+			return nil, fmt.Errorf("tmx: property type %s: %w", t.Name, err)
+		}
+		p.PropertyTypes = append(p.PropertyTypes, t)
+	}
+	return p, nil
+}
+
+// PropertyType returns the property type with the given name, or nil if there is none.
+func (p *Project) PropertyType(name string) *PropertyType {
+	for i := range p.PropertyTypes {
+		if p.PropertyTypes[i].Name == name {
+			return &p.PropertyTypes[i]
+		}
+	}
+	return nil
+}
+
+// usableAs reports whether a class may be used as the given kind of thing.
+func (t *PropertyType) usableAs(kind string) bool {
+	for _, u := range t.UseAs {
+		if u == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply fills in the properties of m with p's types. Class properties get all of their members,
+// with the defaults of those they don't set, and enum properties get their EnumValues.
+// As in Tiled, a map, layer, object or Wang set or color whose class is a class type
+// that may be used for it has that class's members as its default properties.
+func (p *Project) Apply(m *Map) error {
+	var err error
+	m.Properties, err = p.apply(m.Properties, m.Class, "map")
+	if err != nil {
+		return err
+	}
+
+	for i := range m.Tilesets {
+		t := &m.Tilesets[i]
+		t.Properties, err = p.apply(t.Properties, t.Class, "tileset")
+		if err != nil {
+			return err
+		}
+		for j := range t.TerrainTypes {
+			tr := &t.TerrainTypes[j]
+			tr.Properties, err = p.apply(tr.Properties, "", "terrain")
+			if err != nil {
+				return err
+			}
+		}
+		for j := range t.Tiles {
+			tile := &t.Tiles[j]
+			tile.Properties, err = p.apply(tile.Properties, tile.Type, "tile")
+			if err != nil {
+				return err
+			}
+		}
+		for j := range t.WangSets {
+			w := &t.WangSets[j]
+			w.Properties, err = p.apply(w.Properties, w.Class, "wangset")
+			if err != nil {
+				return err
+			}
+			for k := range w.Colors {
+				c := &w.Colors[k]
+				c.Properties, err = p.apply(c.Properties, c.Class, "wangcolor")
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return m.Walk(func(n LayerNode, s LayerState) error {
+		var err error
+		switch {
+		case n.Layer != nil:
+			l := n.Layer
+			l.Properties, err = p.apply(l.Properties, l.Class, "layer")
+		case n.ObjectGroup != nil:
+			g := n.ObjectGroup
+			g.Properties, err = p.apply(g.Properties, g.Class, "layer")
+			for j := 0; err == nil && j < len(g.Objects); j++ {
+				o := &g.Objects[j]
+				o.Properties, err = p.apply(o.Properties, o.Type, "object")
+			}
+		case n.ImageLayer != nil:
+			l := n.ImageLayer
+			l.Properties, err = p.apply(l.Properties, l.Class, "layer")
+		case n.Group != nil:
+			g := n.Group
+			g.Properties, err = p.apply(g.Properties, g.Class, "layer")
+		}
+		return err
+	})
+}
+
+// apply returns ps with the members of the class, if it may be used as kind, and with each property filled in.
+func (p *Project) apply(ps Properties, class, kind string) (Properties, error) {
+	if t := p.PropertyType(class); t != nil && t.Type == "class" && t.usableAs(kind) {
+		ps = withMembers(t, ps)
+	}
+	for i := range ps {
+		err := p.fill(&ps[i], nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ps, nil
+}
+
+// fill fills in prop from its type. Classes already being filled in, named by outer,
+// are left alone, so that recursive classes end.
+func (p *Project) fill(prop *Property, outer []string) error {
+	t := p.PropertyType(prop.PropertyType)
+	if t != nil && t.Type == "enum" {
+		var err error
+		prop.EnumValues, err = t.enumValues(prop.Value)
+		if err != nil {
+			return fmt.Errorf("tmx: property %s: %w", prop.Name, err)
+		}
+		return nil
+	}
+	if prop.Type != "class" {
+		return nil
+	}
+
+	if t != nil && t.Type == "class" {
+		for _, name := range outer {
+			if name == t.Name {
+				return nil
+			}
+		}
+		outer = append(outer, t.Name)
+		prop.Properties = withMembers(t, prop.Properties)
+	}
+	for i := range prop.Properties {
+		err := p.fill(&prop.Properties[i], outer)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withMembers returns the members of the class t, in order, as set by ps or with their defaults,
+// followed by the properties in ps that aren't members of t.
+func withMembers(t *PropertyType, ps Properties) Properties {
+	all := make(Properties, 0, len(t.Members)+len(ps))
+	for _, m := range t.Members {
+		x := ps.Get(m.Name)
+		if x == nil {
+			all = append(all, m.clone())
+			continue
+		}
+		set := *x
+		// Class members decoded from JSON have their types guessed from their values.
+		set.Type = m.Type
+		set.PropertyType = m.PropertyType
+		all = append(all, set)
+	}
+	for _, x := range ps {
+		if t.Members.Get(x.Name) == nil {
+			all = append(all, x)
+		}
+	}
+	return all
+}
+
+// clone returns a copy of p that shares no members with it.
+func (p *Property) clone() Property {
+	x := *p
+	if p.Properties != nil {
+		x.Properties = make(Properties, len(p.Properties))
+		for i := range p.Properties {
+			x.Properties[i] = p.Properties[i].clone()
+		}
+	}
+	if p.EnumValues != nil {
+		x.EnumValues = append([]string(nil), p.EnumValues...)
+	}
+	return x
+}
+
+// enumValues returns the names of the values that value holds, as stored for t.
+func (t *PropertyType) enumValues(value string) ([]string, error) {
+	if t.StorageType != "int" {
+		if value == "" {
+			return []string{}, nil
+		}
+		if t.ValuesAsFlags {
+			return strings.Split(value, ","), nil
+		}
+		return []string{value}, nil
+	}
+
+	if value == "" {
+		// A property with no value has the default, 0.
+		value = "0"
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	if t.ValuesAsFlags {
+		for i, name := range t.Values {
+			if n&(1<<i) != 0 {
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	}
+	if n < 0 || n >= len(t.Values) {
+		return nil, fmt.Errorf("no value %d in enum %s", n, t.Name)
+	}
+	return append(names, t.Values[n]), nil
+}
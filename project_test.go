@@ -0,0 +1,276 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eaburns/eq"
+)
+
+func TestProject(t *testing.T) {
+	p, err := DecodeProject(strings.NewReader(testProject))
+	if err != nil {
+		t.Fatalf("unexpected project decode error: %v", err)
+	}
+	if len(p.PropertyTypes) != 9 {
+		t.Fatalf("expected 9 property types, got %d", len(p.PropertyTypes))
+	}
+	if pt := p.PropertyType("DoorKind"); pt == nil || pt.Type != "enum" || pt.StorageType != "int" || len(pt.Values) != 3 {
+		t.Fatalf("wrong DoorKind type: %+v", pt)
+	}
+
+	m, err := Decode(strings.NewReader(testProjectMap))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	err = p.Apply(m)
+	if err != nil {
+		t.Fatalf("unexpected apply error: %v", err)
+	}
+
+	want := Properties{
+		{Name: "difficulty", Type: "int", Value: "3"},
+		{Name: "music", Type: "file", Value: "theme.ogg"},
+		{Name: "door", Type: "class", PropertyType: "Door", Properties: Properties{
+			{Name: "locked", Type: "bool", Value: "true"},
+			{Name: "code", Type: "int", Value: "0"},
+			{Name: "kind", Type: "int", PropertyType: "DoorKind", Value: "2", EnumValues: []string{"stone"}},
+			{Name: "key", Type: "class", PropertyType: "Key", Properties: Properties{
+				{Name: "color", PropertyType: "KeyColor", Value: "red", EnumValues: []string{"red"}},
+				{Name: "shape", Value: "round"},
+			}},
+		}},
+		{Name: "tags", PropertyType: "Tags", Value: "wet,dark", EnumValues: []string{"wet", "dark"}},
+		{Name: "mask", Type: "int", PropertyType: "Layers", Value: "5", EnumValues: []string{"ground", "sky"}},
+		{Name: "none", Type: "int", PropertyType: "Layers", Value: "0", EnumValues: []string{}},
+		{Name: "blank", Type: "int", PropertyType: "DoorKind", EnumValues: []string{"wood"}},
+		{Name: "unset", Type: "int", PropertyType: "Layers", EnumValues: []string{}},
+	}
+	if !eq.Deep(m.Properties, want) {
+		t.Errorf("unequal map properties:\n%+v\n------\n%+v", m.Properties, want)
+	}
+
+	ts := &m.Tilesets[0]
+	if !eq.Deep(ts.Properties, Properties{{Name: "climate", Value: "temperate"}}) {
+		t.Errorf("wrong tileset properties: %+v", ts.Properties)
+	}
+	wantTiles := []Properties{
+		{{Name: "loot", Value: "gold"}},
+		{{Name: "loot", Value: "silver"}},
+	}
+	for i, want := range wantTiles {
+		if tile := &ts.Tiles[i]; tile.Type != "Chest" || !eq.Deep(tile.Properties, want) {
+			t.Errorf("wrong tile %d: %+v", i, tile)
+		}
+	}
+
+	objs := m.ObjectGroups[0].Objects
+	wantObj := Properties{
+		{Name: "locked", Type: "bool", Value: "false"},
+		{Name: "code", Type: "int", Value: "1234"},
+		{Name: "kind", Type: "int", PropertyType: "DoorKind", Value: "1", EnumValues: []string{"iron"}},
+		{Name: "key", Type: "class", PropertyType: "Key", Properties: Properties{
+			{Name: "color", PropertyType: "KeyColor", Value: "gold", EnumValues: []string{"gold"}},
+			{Name: "shape", Value: "round"},
+		}},
+		{Name: "note", Value: "extra"},
+	}
+	if !eq.Deep(objs[0].Properties, wantObj) {
+		t.Errorf("unequal object properties:\n%+v\n------\n%+v", objs[0].Properties, wantObj)
+	}
+	// Key may only be used as a property, not an object.
+	if len(objs[1].Properties) != 0 {
+		t.Errorf("expected no properties for a Key object, got %+v", objs[1].Properties)
+	}
+	// Tiled 1.9 wrote an object's type as its class.
+	if objs[2].Type != "Door" || len(objs[2].Properties) != 4 || objs[2].Properties.Get("code").Value != "0" {
+		t.Errorf("wrong Door object written as a class: %+v", objs[2])
+	}
+
+	// Defaults must not be shared between properties.
+	objs[0].Properties.Get("key").Properties[1].Value = "square"
+	if p.PropertyType("Key").Members[1].Value != "round" {
+		t.Errorf("applied defaults share storage with the project")
+	}
+}
+
+func TestProjectJSONMembers(t *testing.T) {
+	p, err := DecodeProject(strings.NewReader(testProject))
+	if err != nil {
+		t.Fatalf("unexpected project decode error: %v", err)
+	}
+	m, err := DecodeJSON(strings.NewReader(`{
+ "type": "map", "orientation": "orthogonal", "width": 1, "height": 1, "tilewidth": 16, "tileheight": 16,
+ "properties": [{"name": "door", "type": "class", "propertytype": "Door", "value": {"code": 7, "key": {"shape": "star"}}}],
+ "layers": []
+}`))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	err = p.Apply(m)
+	if err != nil {
+		t.Fatalf("unexpected apply error: %v", err)
+	}
+
+	key := m.Properties.Get("door").Member("key")
+	if key == nil || key.PropertyType != "Key" || len(key.Properties) != 2 {
+		t.Fatalf("wrong key member: %+v", key)
+	}
+	if s := key.Member("shape"); s.Value != "star" || s.Type != "" {
+		t.Errorf("wrong shape member: %+v", s)
+	}
+}
+
+func TestBadProject(t *testing.T) {
+	p, err := DecodeProject(strings.NewReader(testProject))
+	if err != nil {
+		t.Fatalf("unexpected project decode error: %v", err)
+	}
+	for _, v := range []string{"7", "-1", "stone"} {
+		m := &Map{Properties: Properties{{Name: "kind", Type: "int", PropertyType: "DoorKind", Value: v}}}
+		err = p.Apply(m)
+		if err == nil {
+			t.Errorf("expected error for enum value %s", v)
+		}
+	}
+}
+
+var testProject = `{
+    "automappingRulesFile": "",
+    "commands": [],
+    "extensionsPath": "extensions",
+    "folders": ["."],
+    "propertyTypes": [
+        {
+            "id": 1,
+            "name": "Door",
+            "type": "class",
+            "color": "#ffa0a0a4",
+            "drawFill": true,
+            "useAs": ["property", "object"],
+            "members": [
+                {"name": "locked", "type": "bool", "value": false},
+                {"name": "code", "type": "int", "value": 0},
+                {"name": "kind", "type": "int", "propertyType": "DoorKind", "value": 1},
+                {"name": "key", "type": "class", "propertyType": "Key", "value": {}}
+            ]
+        },
+        {
+            "id": 2,
+            "name": "DoorKind",
+            "type": "enum",
+            "storageType": "int",
+            "values": ["wood", "iron", "stone"],
+            "valuesAsFlags": false
+        },
+        {
+            "id": 3,
+            "name": "Key",
+            "type": "class",
+            "useAs": ["property"],
+            "members": [
+                {"name": "color", "type": "string", "propertyType": "KeyColor", "value": "gold"},
+                {"name": "shape", "type": "string", "value": "round"}
+            ]
+        },
+        {
+            "id": 4,
+            "name": "KeyColor",
+            "type": "enum",
+            "storageType": "string",
+            "values": ["gold", "red"],
+            "valuesAsFlags": false
+        },
+        {
+            "id": 5,
+            "name": "Tags",
+            "type": "enum",
+            "storageType": "string",
+            "values": ["wet", "dark", "loud"],
+            "valuesAsFlags": true
+        },
+        {
+            "id": 6,
+            "name": "Layers",
+            "type": "enum",
+            "storageType": "int",
+            "values": ["ground", "water", "sky"],
+            "valuesAsFlags": true
+        },
+        {
+            "id": 7,
+            "name": "Level",
+            "type": "class",
+            "useAs": ["map"],
+            "members": [
+                {"name": "difficulty", "type": "int", "value": 1},
+                {"name": "music", "type": "file", "value": ""}
+            ]
+        },
+        {
+            "id": 8,
+            "name": "Chest",
+            "type": "class",
+            "useAs": ["tile"],
+            "members": [
+                {"name": "loot", "type": "string", "value": "gold"}
+            ]
+        },
+        {
+            "id": 9,
+            "name": "Biome",
+            "type": "class",
+            "useAs": ["tileset"],
+            "members": [
+                {"name": "climate", "type": "string", "value": "temperate"}
+            ]
+        }
+    ]
+}
+`
+
+var testProjectMap = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" class="Level" orientation="orthogonal" width="1" height="1" tilewidth="16" tileheight="16">
+ <properties>
+  <property name="difficulty" type="int" value="3"/>
+  <property name="music" type="file" value="theme.ogg"/>
+  <property name="door" type="class" propertytype="Door">
+   <properties>
+    <property name="locked" type="bool" value="true"/>
+    <property name="kind" type="int" propertytype="DoorKind" value="2"/>
+    <property name="key" type="class" propertytype="Key">
+     <properties>
+      <property name="color" propertytype="KeyColor" value="red"/>
+     </properties>
+    </property>
+   </properties>
+  </property>
+  <property name="tags" propertytype="Tags" value="wet,dark"/>
+  <property name="mask" type="int" propertytype="Layers" value="5"/>
+  <property name="none" type="int" propertytype="Layers" value="0"/>
+  <property name="blank" type="int" propertytype="DoorKind" value=""/>
+  <property name="unset" type="int" propertytype="Layers"/>
+ </properties>
+ <tileset firstgid="1" name="chests" class="Biome" tilewidth="16" tileheight="16" tilecount="2" columns="2">
+  <image source="chests.png" width="32" height="16"/>
+  <tile id="0" type="Chest"/>
+  <tile id="1" class="Chest">
+   <properties>
+    <property name="loot" value="silver"/>
+   </properties>
+  </tile>
+ </tileset>
+ <objectgroup id="1" name="Doors">
+  <object id="1" type="Door" x="0" y="0">
+   <properties>
+    <property name="code" type="int" value="1234"/>
+    <property name="note" value="extra"/>
+   </properties>
+  </object>
+  <object id="2" type="Key" x="0" y="0"/>
+  <object id="3" class="Door" x="0" y="0"/>
+ </objectgroup>
+</map>
+`
@@ -0,0 +1,274 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import "math"
+
+// TileToPixel returns the pixel position of the top-left corner of the
+// bounding box of the tile at x, y, following the layout of Tiled's
+// renderer for m's orientation. For isometric maps, the tile's top corner
+// is TileWidth/2 to the right of the returned position.
+func (m *Map) TileToPixel(x, y int) (px, py float64) {
+	switch m.Orientation {
+	case "isometric":
+		tw, th := float64(m.TileWidth), float64(m.TileHeight)
+		originX := float64(m.Height * m.TileWidth / 2)
+		return float64(x-y)*tw/2 + originX - tw/2, float64(x+y) * th / 2
+
+	case "staggered", "hexagonal":
+		p := m.renderParams()
+		if p.staggerX {
+			py = float64(y * (p.tileHeight + p.sideLengthY))
+			if p.doStaggerX(x) {
+				py += float64(p.rowHeight)
+			}
+			return float64(x * p.columnWidth), py
+		}
+		px = float64(x * (p.tileWidth + p.sideLengthX))
+		if p.doStaggerY(y) {
+			px += float64(p.columnWidth)
+		}
+		return px, float64(y * p.rowHeight)
+	}
+	return float64(x * m.TileWidth), float64(y * m.TileHeight)
+}
+
+// PixelToTile returns the coordinates of the tile that contains the pixel position px, py.
+// It isn't the inverse of TileToPixel except in orthogonal maps: the corner of a tile's
+// bounding box lies in a neighboring tile. The position from TileToPixel plus half of
+// TileWidth and TileHeight, the center of the bounding box, gives back the same tile.
+func (m *Map) PixelToTile(px, py float64) (x, y int) {
+	switch m.Orientation {
+	case "isometric":
+		tw, th := float64(m.TileWidth), float64(m.TileHeight)
+		originX := float64(m.Height * m.TileWidth / 2)
+		tx := (px - originX) / tw
+		ty := py / th
+		return int(math.Floor(ty + tx)), int(math.Floor(ty - tx))
+
+	case "staggered":
+		return m.renderParams().staggeredPixelToTile(px, py)
+
+	case "hexagonal":
+		return m.renderParams().hexagonalPixelToTile(px, py)
+	}
+	return int(math.Floor(px / float64(m.TileWidth))), int(math.Floor(py / float64(m.TileHeight)))
+}
+
+// renderParams mirrors the layout parameters of Tiled's hexagonal and staggered renderers.
+type renderParams struct {
+	tileWidth, tileHeight    int
+	sideLengthX, sideLengthY int
+	sideOffsetX, sideOffsetY int
+	columnWidth, rowHeight   int
+	staggerX, staggerEven    bool
+}
+
+func (m *Map) renderParams() renderParams {
+	p := renderParams{
+		tileWidth:   m.TileWidth &^ 1,
+		tileHeight:  m.TileHeight &^ 1,
+		staggerX:    m.StaggerAxis == "x",
+		staggerEven: m.StaggerIndex == "even",
+	}
+	if m.Orientation == "hexagonal" {
+		if p.staggerX {
+			p.sideLengthX = m.HexSideLength
+		} else {
+			p.sideLengthY = m.HexSideLength
+		}
+	}
+	p.sideOffsetX = (p.tileWidth - p.sideLengthX) / 2
+	p.sideOffsetY = (p.tileHeight - p.sideLengthY) / 2
+	p.columnWidth = p.sideOffsetX + p.sideLengthX
+	p.rowHeight = p.sideOffsetY + p.sideLengthY
+	return p
+}
+
+func (p renderParams) doStaggerX(x int) bool {
+	return p.staggerX && (x&1 != 0) != p.staggerEven
+}
+
+func (p renderParams) doStaggerY(y int) bool {
+	return !p.staggerX && (y&1 != 0) != p.staggerEven
+}
+
+func (p renderParams) hexagonalPixelToTile(px, py float64) (int, int) {
+	if p.staggerX {
+		if p.staggerEven {
+			px -= float64(p.tileWidth)
+		} else {
+			px -= float64(p.sideOffsetX)
+		}
+	} else {
+		if p.staggerEven {
+			py -= float64(p.tileHeight)
+		} else {
+			py -= float64(p.sideOffsetY)
+		}
+	}
+
+	// Start with the coordinates of a grid-aligned tile.
+	cw2, rh2 := float64(p.columnWidth*2), float64(p.rowHeight*2)
+	refX := int(math.Floor(px / cw2))
+	refY := int(math.Floor(py / rh2))
+	relX := px - float64(refX)*cw2
+	relY := py - float64(refY)*rh2
+
+	// Adjust the reference point to the correct tile coordinates.
+	if p.staggerX {
+		refX *= 2
+		if p.staggerEven {
+			refX++
+		}
+	} else {
+		refY *= 2
+		if p.staggerEven {
+			refY++
+		}
+	}
+
+	// Find the nearest of the centers of the tiles around the reference point.
+	var centers [4][2]float64
+	var offsets [4][2]int
+	if p.staggerX {
+		left := float64(p.sideLengthX / 2)
+		centerX := left + float64(p.columnWidth)
+		centerY := float64(p.tileHeight / 2)
+		centers = [4][2]float64{
+			{left, centerY},
+			{centerX, centerY - float64(p.rowHeight)},
+			{centerX, centerY + float64(p.rowHeight)},
+			{centerX + float64(p.columnWidth), centerY},
+		}
+		offsets = [4][2]int{{0, 0}, {1, -1}, {1, 0}, {2, 0}}
+	} else {
+		top := float64(p.sideLengthY / 2)
+		centerX := float64(p.tileWidth / 2)
+		centerY := top + float64(p.rowHeight)
+		centers = [4][2]float64{
+			{centerX, top},
+			{centerX - float64(p.columnWidth), centerY},
+			{centerX + float64(p.columnWidth), centerY},
+			{centerX, centerY + float64(p.rowHeight)},
+		}
+		offsets = [4][2]int{{0, 0}, {-1, 1}, {0, 1}, {0, 2}}
+	}
+
+	nearest := 0
+	minDist := math.Inf(1)
+	for i, c := range centers {
+		dx, dy := c[0]-relX, c[1]-relY
+		if d := dx*dx + dy*dy; d < minDist {
+			minDist = d
+			nearest = i
+		}
+	}
+	return refX + offsets[nearest][0], refY + offsets[nearest][1]
+}
+
+func (p renderParams) staggeredPixelToTile(px, py float64) (int, int) {
+	if p.staggerX {
+		if p.staggerEven {
+			px -= float64(p.sideOffsetX)
+		}
+	} else {
+		if p.staggerEven {
+			py -= float64(p.sideOffsetY)
+		}
+	}
+
+	// Start with the coordinates of a grid-aligned tile.
+	tw, th := float64(p.tileWidth), float64(p.tileHeight)
+	x := int(math.Floor(px / tw))
+	y := int(math.Floor(py / th))
+	relX := px - float64(x)*tw
+	relY := py - float64(y)*th
+
+	// Adjust the reference point to the correct tile coordinates.
+	if p.staggerX {
+		x *= 2
+		if p.staggerEven {
+			x++
+		}
+	} else {
+		y *= 2
+		if p.staggerEven {
+			y++
+		}
+	}
+
+	// Check whether the point is in one of the corners, which belong to neighboring tiles.
+	yPos := relX * (th / tw)
+	sideOffsetY := float64(p.sideOffsetY)
+	switch {
+	case sideOffsetY-yPos > relY:
+		return p.topLeft(x, y)
+	case -sideOffsetY+yPos > relY:
+		return p.topRight(x, y)
+	case sideOffsetY+yPos < relY:
+		return p.bottomLeft(x, y)
+	case sideOffsetY*3-yPos < relY:
+		return p.bottomRight(x, y)
+	}
+	return x, y
+}
+
+func (p renderParams) staggered(x, y int) bool {
+	if p.staggerX {
+		return (x&1 != 0) != p.staggerEven
+	}
+	return (y&1 != 0) != p.staggerEven
+}
+
+func (p renderParams) topLeft(x, y int) (int, int) {
+	if p.staggerX {
+		if p.staggered(x, y) {
+			return x - 1, y
+		}
+		return x - 1, y - 1
+	}
+	if p.staggered(x, y) {
+		return x, y - 1
+	}
+	return x - 1, y - 1
+}
+
+func (p renderParams) topRight(x, y int) (int, int) {
+	if p.staggerX {
+		if p.staggered(x, y) {
+			return x + 1, y
+		}
+		return x + 1, y - 1
+	}
+	if p.staggered(x, y) {
+		return x + 1, y - 1
+	}
+	return x, y - 1
+}
+
+func (p renderParams) bottomLeft(x, y int) (int, int) {
+	if p.staggerX {
+		if p.staggered(x, y) {
+			return x - 1, y + 1
+		}
+		return x - 1, y
+	}
+	if p.staggered(x, y) {
+		return x, y + 1
+	}
+	return x - 1, y + 1
+}
+
+func (p renderParams) bottomRight(x, y int) (int, int) {
+	if p.staggerX {
+		if p.staggered(x, y) {
+			return x + 1, y + 1
+		}
+		return x + 1, y
+	}
+	if p.staggered(x, y) {
+		return x + 1, y + 1
+	}
+	return x, y + 1
+}
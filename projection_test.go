@@ -0,0 +1,103 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStaggerAttributes(t *testing.T) {
+	m, err := Decode(strings.NewReader(testHexagonal))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if m.Orientation != "hexagonal" || m.RenderOrder != "left-up" || m.StaggerAxis != "x" || m.StaggerIndex != "even" || m.HexSideLength != 6 {
+		t.Fatalf("wrong attributes: %+v", m)
+	}
+
+	m, err = Decode(strings.NewReader(testXml))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if m.RenderOrder != "right-down" {
+		t.Fatalf("expected default render order, got %q", m.RenderOrder)
+	}
+}
+
+func TestTileToPixel(t *testing.T) {
+	tests := []struct {
+		m      Map
+		x, y   int
+		px, py float64
+	}{
+		{Map{Orientation: "orthogonal", TileWidth: 16, TileHeight: 8}, 3, -2, 48, -16},
+		{Map{Orientation: "isometric", Height: 10, TileWidth: 64, TileHeight: 32}, 0, 0, 288, 0},
+		{Map{Orientation: "isometric", Height: 10, TileWidth: 64, TileHeight: 32}, 1, 0, 320, 16},
+		{Map{Orientation: "isometric", Height: 10, TileWidth: 64, TileHeight: 32}, 0, 1, 256, 16},
+		{Map{Orientation: "staggered", StaggerAxis: "y", StaggerIndex: "odd", TileWidth: 64, TileHeight: 32}, 1, 1, 96, 16},
+		{Map{Orientation: "staggered", StaggerAxis: "y", StaggerIndex: "even", TileWidth: 64, TileHeight: 32}, 1, 1, 64, 16},
+		{Map{Orientation: "staggered", StaggerAxis: "x", StaggerIndex: "odd", TileWidth: 64, TileHeight: 32}, 1, 1, 32, 48},
+		{Map{Orientation: "hexagonal", StaggerAxis: "y", StaggerIndex: "odd", TileWidth: 14, TileHeight: 12, HexSideLength: 6}, 1, 1, 21, 9},
+		{Map{Orientation: "hexagonal", StaggerAxis: "y", StaggerIndex: "odd", TileWidth: 14, TileHeight: 12, HexSideLength: 6}, 0, 2, 0, 18},
+		{Map{Orientation: "hexagonal", StaggerAxis: "x", StaggerIndex: "even", TileWidth: 12, TileHeight: 14, HexSideLength: 6}, 2, 1, 18, 21},
+	}
+	for i, test := range tests {
+		px, py := test.m.TileToPixel(test.x, test.y)
+		if px != test.px || py != test.py {
+			t.Errorf("%d: TileToPixel(%d, %d) = %v, %v, expected %v, %v", i, test.x, test.y, px, py, test.px, test.py)
+		}
+	}
+}
+
+func TestPixelToTile(t *testing.T) {
+	maps := []Map{
+		{Orientation: "orthogonal", TileWidth: 16, TileHeight: 16},
+		{Orientation: "isometric", Height: 10, TileWidth: 64, TileHeight: 32},
+		{Orientation: "staggered", StaggerAxis: "y", StaggerIndex: "odd", TileWidth: 64, TileHeight: 32},
+		{Orientation: "staggered", StaggerAxis: "y", StaggerIndex: "even", TileWidth: 64, TileHeight: 32},
+		{Orientation: "staggered", StaggerAxis: "x", StaggerIndex: "odd", TileWidth: 64, TileHeight: 32},
+		{Orientation: "staggered", StaggerAxis: "x", StaggerIndex: "even", TileWidth: 64, TileHeight: 32},
+		{Orientation: "hexagonal", StaggerAxis: "y", StaggerIndex: "odd", TileWidth: 14, TileHeight: 12, HexSideLength: 6},
+		{Orientation: "hexagonal", StaggerAxis: "y", StaggerIndex: "even", TileWidth: 14, TileHeight: 12, HexSideLength: 6},
+		{Orientation: "hexagonal", StaggerAxis: "x", StaggerIndex: "odd", TileWidth: 12, TileHeight: 14, HexSideLength: 6},
+		{Orientation: "hexagonal", StaggerAxis: "x", StaggerIndex: "even", TileWidth: 12, TileHeight: 14, HexSideLength: 6},
+	}
+	for i, m := range maps {
+		for y := -3; y <= 3; y++ {
+			for x := -3; x <= 3; x++ {
+				px, py := m.TileToPixel(x, y)
+				cx, cy := px+float64(m.TileWidth)/2, py+float64(m.TileHeight)/2
+				gx, gy := m.PixelToTile(cx, cy)
+				if gx != x || gy != y {
+					t.Errorf("map %d: PixelToTile(%v, %v) = %d, %d, expected %d, %d", i, cx, cy, gx, gy, x, y)
+				}
+			}
+		}
+	}
+
+	// The corners of a tile's bounding box belong to its neighbors.
+	if x, y := maps[1].PixelToTile(289, 1); x != -1 || y != 0 {
+		t.Errorf("got %d, %d for the top-left corner of isometric tile 0, 0, expected -1, 0", x, y)
+	}
+	m := maps[2]
+	if x, y := m.PixelToTile(1, 1); x != -1 || y != -1 {
+		t.Errorf("got %d, %d for the top-left corner, expected -1, -1", x, y)
+	}
+	if x, y := m.PixelToTile(63, 31); x != 0 || y != 1 {
+		t.Errorf("got %d, %d for the bottom-right corner, expected 0, 1", x, y)
+	}
+}
+
+var testHexagonal = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.9" tiledversion="1.9.2" orientation="hexagonal" renderorder="left-up" width="4" height="4" tilewidth="12" tileheight="14" infinite="0" hexsidelength="6" staggeraxis="x" staggerindex="even" nextlayerid="2" nextobjectid="1">
+ <layer id="1" name="Ground" width="4" height="4">
+  <data encoding="csv">
+0,0,0,0,
+0,0,0,0,
+0,0,0,0,
+0,0,0,0
+</data>
+ </layer>
+</map>
+`
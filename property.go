@@ -0,0 +1,109 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+type Properties []Property
+
+// Get returns the property with the given name, or nil if there is none.
+func (ps Properties) Get(name string) *Property {
+	for i := range ps {
+		if ps[i].Name == name {
+			return &ps[i]
+		}
+	}
+	return nil
+}
+
+func (p *Property) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type property Property
+	var x struct {
+		property
+		Text string `xml:",chardata"`
+	}
+	err := d.DecodeElement(&x, &start)
+	if err != nil {
+		return err
+	}
+	*p = Property(x.property)
+
+	if p.Type != "class" && !hasAttr(start, "value") {
+		p.Value = x.Text
+	}
+	return nil
+}
+
+func hasAttr(start xml.StartElement, name string) bool {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Member returns the member of a class property with the given name,
+// or nil if it isn't set.
+func (p *Property) Member(name string) *Property {
+	return p.Properties.Get(name)
+}
+
+func (p *Property) Bool() (bool, error) {
+	return strconv.ParseBool(p.Value)
+}
+
+func (p *Property) Int() (int, error) {
+	return strconv.Atoi(p.Value)
+}
+
+func (p *Property) Float() (float64, error) {
+	return strconv.ParseFloat(p.Value, 64)
+}
+
+// Color returns the value of a color property.
+// An unset color is returned as fully transparent black.
+func (p *Property) Color() (color.NRGBA, error) {
+	if p.Value == "" {
+		return color.NRGBA{}, nil
+	}
+	return ParseColor(p.Value)
+}
+
+// File returns the path of a file property, relative to the file that contains it.
+func (p *Property) File() string {
+	return p.Value
+}
+
+// ObjectRef returns the ID of the object that an object property refers to,
+// or 0 if it refers to none.
+func (p *Property) ObjectRef() (int, error) {
+	if p.Value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(p.Value)
+}
+
+// ParseColor parses a color as written by Tiled, #AARRGGBB or #RRGGBB,
+// with or without the leading #.
+func ParseColor(s string) (color.NRGBA, error) {
+	h := strings.TrimPrefix(s, "#")
+	if len(h) != 6 && len(h) != 8 {
+		return color.NRGBA{}, fmt.Errorf("tmx: malformed color %q", s)
+	}
+	b, err := hex.DecodeString(h)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("tmx: malformed color %q", s)
+	}
+	if len(b) == 3 {
+		return color.NRGBA{R: b[0], G: b[1], B: b[2], A: 0xff}, nil
+	}
+	return color.NRGBA{A: b[0], R: b[1], G: b[2], B: b[3]}, nil
+}
@@ -0,0 +1,105 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestTypedProperties(t *testing.T) {
+	m, err := Decode(strings.NewReader(testProperties))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	ps := m.Properties
+
+	if b, err := ps.Get("solid").Bool(); err != nil || !b {
+		t.Errorf("got %v, %v for bool", b, err)
+	}
+	if n, err := ps.Get("hp").Int(); err != nil || n != -12 {
+		t.Errorf("got %v, %v for int", n, err)
+	}
+	if f, err := ps.Get("speed").Float(); err != nil || f != 2.5 {
+		t.Errorf("got %v, %v for float", f, err)
+	}
+	if c, err := ps.Get("tint").Color(); err != nil || c != (color.NRGBA{R: 0x11, G: 0x22, B: 0x33, A: 0x80}) {
+		t.Errorf("got %v, %v for color", c, err)
+	}
+	if c, err := ps.Get("shade").Color(); err != nil || c != (color.NRGBA{R: 0xaa, G: 0xbb, B: 0xcc, A: 0xff}) {
+		t.Errorf("got %v, %v for color without alpha", c, err)
+	}
+	if c, err := ps.Get("unset").Color(); err != nil || c != (color.NRGBA{}) {
+		t.Errorf("got %v, %v for unset color", c, err)
+	}
+	if f := ps.Get("music").File(); f != "../audio/theme.ogg" {
+		t.Errorf("got %q for file", f)
+	}
+	if id, err := ps.Get("exit").ObjectRef(); err != nil || id != 7 {
+		t.Errorf("got %v, %v for object", id, err)
+	}
+	if id, err := ps.Get("nothing").ObjectRef(); err != nil || id != 0 {
+		t.Errorf("got %v, %v for empty object", id, err)
+	}
+	if s := ps.Get("intro").Value; s != "Once upon a time\nthere was a map." {
+		t.Errorf("got %q for multiline string", s)
+	}
+	if ps.Get("missing") != nil {
+		t.Errorf("expected no property named missing")
+	}
+	if _, err := ps.Get("intro").Int(); err == nil {
+		t.Errorf("expected error for a string as an int")
+	}
+	if _, err := ps.Get("bad").Color(); err == nil {
+		t.Errorf("expected error for a malformed color")
+	}
+
+	door := ps.Get("door")
+	if door.Type != "class" || door.PropertyType != "Door" || door.Value != "" {
+		t.Fatalf("wrong class property: %+v", door)
+	}
+	if b, err := door.Member("locked").Bool(); err != nil || !b {
+		t.Errorf("got %v, %v for class member", b, err)
+	}
+	key := door.Member("key")
+	if key == nil || key.PropertyType != "Key" {
+		t.Fatalf("wrong nested class member: %+v", key)
+	}
+	if key.Member("color").Value != "red" || key.Member("color").PropertyType != "KeyColor" {
+		t.Errorf("wrong enum member: %+v", key.Member("color"))
+	}
+	if door.Member("missing") != nil {
+		t.Errorf("expected no member named missing")
+	}
+}
+
+var testProperties = `
+<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.9" orientation="orthogonal" width="1" height="1" tilewidth="16" tileheight="16">
+ <properties>
+  <property name="solid" type="bool" value="true"/>
+  <property name="hp" type="int" value="-12"/>
+  <property name="speed" type="float" value="2.5"/>
+  <property name="tint" type="color" value="#80112233"/>
+  <property name="shade" type="color" value="#aabbcc"/>
+  <property name="unset" type="color" value=""/>
+  <property name="bad" type="color" value="#12"/>
+  <property name="music" type="file" value="../audio/theme.ogg"/>
+  <property name="exit" type="object" value="7"/>
+  <property name="nothing" type="object" value="0"/>
+  <property name="intro">Once upon a time
+there was a map.</property>
+  <property name="door" type="class" propertytype="Door">
+   <properties>
+    <property name="locked" type="bool" value="true"/>
+    <property name="key" type="class" propertytype="Key">
+     <properties>
+      <property name="color" propertytype="KeyColor" value="red"/>
+     </properties>
+    </property>
+   </properties>
+  </property>
+ </properties>
+</map>
+`
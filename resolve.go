@@ -0,0 +1,74 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// A Resolver opens the files that a map refers to, such as external tilesets.
+// Names are slash-separated and have already been joined with the directory
+// of the file that refers to them.
+type Resolver interface {
+	Open(name string) (io.ReadCloser, error)
+}
+
+// A DirResolver is a Resolver that can also list the files in a directory,
+// as needed for the patterns of world files.
+type DirResolver interface {
+	Resolver
+	ReadDir(name string) ([]fs.DirEntry, error)
+}
+
+// ResolverFunc adapts an ordinary function to the Resolver interface.
+type ResolverFunc func(name string) (io.ReadCloser, error)
+
+func (f ResolverFunc) Open(name string) (io.ReadCloser, error) {
+	return f(name)
+}
+
+// Dir is a Resolver that opens files on disk, relative to the named directory.
+type Dir string
+
+func (d Dir) Open(name string) (io.ReadCloser, error) {
+	if !path.IsAbs(name) {
+		name = filepath.Join(string(d), filepath.FromSlash(name))
+	}
+	return os.Open(name)
+}
+
+func (d Dir) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !path.IsAbs(name) {
+		name = filepath.Join(string(d), filepath.FromSlash(name))
+	}
+	return os.ReadDir(name)
+}
+
+// FS returns a Resolver that opens files from fsys. It is also a DirResolver.
+func FS(fsys fs.FS) Resolver {
+	return fsResolver{fsys}
+}
+
+type fsResolver struct {
+	fsys fs.FS
+}
+
+func (r fsResolver) Open(name string) (io.ReadCloser, error) {
+	return r.fsys.Open(name)
+}
+
+func (r fsResolver) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(r.fsys, name)
+}
+
+// resolvePath returns the name of the file ref, as written in the file named from.
+func resolvePath(from, ref string) string {
+	if path.IsAbs(ref) {
+		return ref
+	}
+	return path.Join(path.Dir(from), ref)
+}
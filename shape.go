@@ -0,0 +1,89 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import "encoding/xml"
+
+// Shape is the kind of an object.
+type Shape int
+
+const (
+	ShapeRectangle Shape = iota
+	ShapeEllipse
+	ShapePoint
+	ShapePolygon
+	ShapePolyline
+	ShapeTile
+	ShapeText
+)
+
+var shapeNames = [...]string{
+	ShapeRectangle: "rectangle",
+	ShapeEllipse:   "ellipse",
+	ShapePoint:     "point",
+	ShapePolygon:   "polygon",
+	ShapePolyline:  "polyline",
+	ShapeTile:      "tile",
+	ShapeText:      "text",
+}
+
+func (s Shape) String() string {
+	if s < 0 || int(s) >= len(shapeNames) {
+		return "unknown"
+	}
+	return shapeNames[s]
+}
+
+// Shape returns the kind of o.
+func (o *Object) Shape() Shape {
+	switch {
+	case o.Text != nil:
+		return ShapeText
+	case o.Ellipse != nil:
+		return ShapeEllipse
+	case o.Point != nil:
+		return ShapePoint
+	case o.Polygon != nil:
+		return ShapePolygon
+	case o.Polylines != nil:
+		return ShapePolyline
+	case o.GID != 0:
+		return ShapeTile
+	}
+	return ShapeRectangle
+}
+
+type Text struct {
+	FontFamily string `xml:"fontfamily,attr"`
+	PixelSize  int    `xml:"pixelsize,attr"`
+	Wrap       bool   `xml:"wrap,attr"`
+	Color      string `xml:"color,attr"`
+	Bold       bool   `xml:"bold,attr"`
+	Italic     bool   `xml:"italic,attr"`
+	Underline  bool   `xml:"underline,attr"`
+	Strikeout  bool   `xml:"strikeout,attr"`
+	Kerning    bool   `xml:"kerning,attr"`
+	HAlign     string `xml:"halign,attr"`
+	VAlign     string `xml:"valign,attr"`
+
+	Contents string `xml:",chardata"`
+}
+
+// UnmarshalXML decodes a <text> element, applying Tiled's defaults for omitted attributes.
+func (t *Text) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type text Text
+	x := text{
+		FontFamily: "sans-serif",
+		PixelSize:  16,
+		Color:      "#000000",
+		Kerning:    true,
+		HAlign:     "left",
+		VAlign:     "top",
+	}
+	err := d.DecodeElement(&x, &start)
+	if err != nil {
+		return err
+	}
+	*t = Text(x)
+	return nil
+}
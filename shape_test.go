@@ -0,0 +1,90 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShapes(t *testing.T) {
+	m, err := Decode(strings.NewReader(testShapes))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	want := []Shape{ShapeRectangle, ShapeEllipse, ShapePoint, ShapePolygon, ShapePolyline, ShapeTile, ShapeText, ShapeText}
+	objs := m.ObjectGroups[0].Objects
+	if len(objs) != len(want) {
+		t.Fatalf("expected %d objects, got %d", len(want), len(objs))
+	}
+	for i, o := range objs {
+		if o.Shape() != want[i] {
+			t.Errorf("object %d: got %v, expected %v", i, o.Shape(), want[i])
+		}
+	}
+
+	got := *objs[6].Text
+	text := Text{
+		FontFamily: "Serif",
+		PixelSize:  24,
+		Wrap:       true,
+		Color:      "#ff0000",
+		Bold:       true,
+		Italic:     true,
+		Underline:  true,
+		Strikeout:  true,
+		Kerning:    false,
+		HAlign:     "center",
+		VAlign:     "bottom",
+		Contents:   "Beware of the dog",
+	}
+	if got != text {
+		t.Errorf("got %+v, expected %+v", got, text)
+	}
+
+	got = *objs[7].Text
+	text = Text{
+		FontFamily: "sans-serif",
+		PixelSize:  16,
+		Color:      "#000000",
+		Kerning:    true,
+		HAlign:     "left",
+		VAlign:     "top",
+		Contents:   "Hello",
+	}
+	if got != text {
+		t.Errorf("got %+v, expected %+v", got, text)
+	}
+}
+
+var testShapes = `
+<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.2" orientation="orthogonal" width="1" height="1" tilewidth="16" tileheight="16">
+ <tileset firstgid="1" name="land" tilewidth="16" tileheight="16">
+  <image source="tiles.png" width="48" height="16"/>
+ </tileset>
+ <objectgroup id="1" name="Shapes">
+  <object id="1" x="0" y="0" width="16" height="16"/>
+  <object id="2" x="0" y="0" width="16" height="16">
+   <ellipse/>
+  </object>
+  <object id="3" name="spawn" x="8" y="8">
+   <point/>
+  </object>
+  <object id="4" x="0" y="0">
+   <polygon points="0,0 16,0 16,16"/>
+  </object>
+  <object id="5" x="0" y="0">
+   <polyline points="0,0 16,16"/>
+  </object>
+  <object id="6" gid="2" x="0" y="16" width="16" height="16"/>
+  <object id="7" x="0" y="0" width="96" height="32">
+   <text fontfamily="Serif" pixelsize="24" wrap="1" color="#ff0000" bold="1" italic="1" underline="1" strikeout="1" kerning="0" halign="center" valign="bottom">Beware of the dog</text>
+  </object>
+  <object id="8" x="0" y="0" width="96" height="32">
+   <text>Hello</text>
+  </object>
+ </objectgroup>
+</map>
+`
@@ -0,0 +1,627 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+)
+
+// A StreamHandler receives the parts of a map from DecodeStream as they are decoded.
+// Any of its functions may be nil. If one returns an error, DecodeStream stops and returns it.
+type StreamHandler struct {
+	// Map is called with the map's attributes, properties and tilesets, before any of its layers.
+	Map func(m *Map) error
+
+	// Layer is called with each tile layer once its tiles are decoded.
+	Layer func(l *Layer, s LayerState) error
+
+	// ObjectGroup is called at the end of each object group. If Object is set, Object is
+	// called with each of the group's objects in turn instead of collecting them in g.Objects.
+	ObjectGroup func(g *ObjectGroup, s LayerState) error
+	Object      func(o *Object, g *ObjectGroup) error
+
+	ImageLayer func(l *ImageLayer, s LayerState) error
+
+	// Group is called with each group layer, before its children. g.Layers is empty.
+	Group func(g *Group, s LayerState) error
+}
+
+// DecodeStream decodes a TMX map from r, passing its layers and objects to h as it goes rather
+// than building the whole map in memory. The text of tile data is decoded straight into each
+// layer's GIDs, without ever holding the whole text. Text from its first character reference,
+// CDATA section or comment on is read through the XML decoder instead, which holds each
+// piece of it, such as a whole CDATA section, in memory.
+// Unlike DecodeFile, it neither loads external tilesets nor applies templates.
+func DecodeStream(r io.Reader, h StreamHandler) error {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReaderSize(r, 64*1024)
+	}
+	s := &streamer{r: &streamReader{r: br}, h: h}
+	s.d = xml.NewDecoder(s.r)
+	return s.decodeMap()
+}
+
+type streamer struct {
+	r *streamReader
+	d *xml.Decoder
+	h StreamHandler
+
+	// A token read by the decoder at the end of tile data's text, for next to return.
+	pending xml.Token
+}
+
+// A streamReader is the byte reader that the XML decoder reads from. The decoder reads it
+// a byte at a time, so the text of tile data can be read from it directly after the start
+// of the element that holds it.
+type streamReader struct {
+	r          *bufio.Reader
+	prev, last byte
+}
+
+func (r *streamReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.prev, r.last = r.last, b
+	}
+	return b, err
+}
+
+// Read is only here to satisfy io.Reader. The XML decoder uses ReadByte.
+func (r *streamReader) Read(p []byte) (int, error) {
+	for i := range p {
+		b, err := r.ReadByte()
+		if err != nil {
+			return i, err
+		}
+		p[i] = b
+	}
+	return len(p), nil
+}
+
+// unread unreads the last byte read, which must have been read by the streamer.
+func (r *streamReader) unread() error {
+	r.last = r.prev
+	return r.r.UnreadByte()
+}
+
+// selfClosed reports whether the last start element read was written as <element/>.
+func (r *streamReader) selfClosed() bool {
+	return r.prev == '/' && r.last == '>'
+}
+
+// next returns the next start or end element, skipping everything else.
+func (s *streamer) next() (xml.Token, error) {
+	if t := s.pending; t != nil {
+		s.pending = nil
+		return t, nil
+	}
+	for {
+		t, err := s.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			return t.Copy(), nil
+		case xml.EndElement:
+			return t, nil
+		}
+	}
+}
+
+// decodeAttrs decodes the attributes of start into v, along with any defaults that v's UnmarshalXML sets.
+func decodeAttrs(start xml.StartElement, v interface{}) error {
+	toks := &tokens{start, start.End()}
+	return xml.NewTokenDecoder(toks).Decode(v)
+}
+
+func (s *streamer) decodeMap() error {
+	var start xml.StartElement
+	for {
+		t, err := s.next()
+		if err != nil {
+			return err
+		}
+		if se, ok := t.(xml.StartElement); ok {
+			start = se
+			break
+		}
+	}
+	if start.Name.Local != "map" {
+		return fmt.Errorf("tmx: expected <map>, got <%s>", start.Name.Local)
+	}
+
+	m := new(Map)
+	err := decodeAttrs(start, m)
+	if err != nil {
+		return err
+	}
+	pending := true
+	for {
+		t, err := s.next()
+		if err != nil {
+			return err
+		}
+		se, ok := t.(xml.StartElement)
+		if !ok {
+			break
+		}
+
+		switch se.Name.Local {
+		case "properties":
+			err = s.decodeProperties(se, &m.Properties)
+		case "tileset":
+			var ts Tileset
+			err = s.d.DecodeElement(&ts, &se)
+			m.Tilesets = append(m.Tilesets, ts)
+		case "layer", "objectgroup", "imagelayer", "group":
+			if pending && s.h.Map != nil {
+				err = s.h.Map(m)
+				if err != nil {
+					return err
+				}
+			}
+			pending = false
+			err = s.decodeNode(se, LayerState{Opacity: 1, Visible: true, ParallaxX: 1, ParallaxY: 1})
+		default:
+			err = s.d.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if pending && s.h.Map != nil {
+		return s.h.Map(m)
+	}
+	return nil
+}
+
+// decodeProperties appends the properties of the <properties> element that starts with start to ps.
+func (s *streamer) decodeProperties(start xml.StartElement, ps *Properties) error {
+	var x struct {
+		Properties Properties `xml:"property"`
+	}
+	err := s.d.DecodeElement(&x, &start)
+	*ps = append(*ps, x.Properties...)
+	return err
+}
+
+// decodeNode decodes the layer that starts with start, within a group of state parent.
+func (s *streamer) decodeNode(start xml.StartElement, parent LayerState) error {
+	switch start.Name.Local {
+	case "layer":
+		return s.decodeLayer(start, parent)
+	case "objectgroup":
+		return s.decodeObjectGroup(start, parent)
+	case "imagelayer":
+		var l ImageLayer
+		err := s.d.DecodeElement(&l, &start)
+		if err != nil || s.h.ImageLayer == nil {
+			return err
+		}
+		return s.h.ImageLayer(&l, parent.child(l.Opacity, l.Visible, l.OffsetX, l.OffsetY, l.ParallaxX, l.ParallaxY))
+	case "group":
+		return s.decodeGroup(start, parent)
+	}
+	return s.d.Skip()
+}
+
+func (s *streamer) decodeLayer(start xml.StartElement, parent LayerState) error {
+	l := new(Layer)
+	err := decodeAttrs(start, l)
+	if err != nil {
+		return err
+	}
+	for {
+		t, err := s.next()
+		if err != nil {
+			return err
+		}
+		se, ok := t.(xml.StartElement)
+		if !ok {
+			break
+		}
+		switch se.Name.Local {
+		case "properties":
+			err = s.decodeProperties(se, &l.Properties)
+		case "data":
+			err = s.decodeData(se, l)
+		default:
+			err = s.d.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if s.h.Layer == nil {
+		return nil
+	}
+	return s.h.Layer(l, parent.child(l.Opacity, l.Visible, l.OffsetX, l.OffsetY, l.ParallaxX, l.ParallaxY))
+}
+
+func (s *streamer) decodeObjectGroup(start xml.StartElement, parent LayerState) error {
+	g := new(ObjectGroup)
+	err := decodeAttrs(start, g)
+	if err != nil {
+		return err
+	}
+	for {
+		t, err := s.next()
+		if err != nil {
+			return err
+		}
+		se, ok := t.(xml.StartElement)
+		if !ok {
+			break
+		}
+		switch se.Name.Local {
+		case "properties":
+			err = s.decodeProperties(se, &g.Properties)
+		case "object":
+			var o Object
+			err = s.d.DecodeElement(&o, &se)
+			if err != nil {
+				return err
+			}
+			if s.h.Object != nil {
+				err = s.h.Object(&o, g)
+			} else {
+				g.Objects = append(g.Objects, o)
+			}
+		default:
+			err = s.d.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if s.h.ObjectGroup == nil {
+		return nil
+	}
+	return s.h.ObjectGroup(g, parent.child(g.Opacity, g.Visible, g.OffsetX, g.OffsetY, g.ParallaxX, g.ParallaxY))
+}
+
+func (s *streamer) decodeGroup(start xml.StartElement, parent LayerState) error {
+	g := new(Group)
+	err := decodeAttrs(start, g)
+	if err != nil {
+		return err
+	}
+	state := parent.child(g.Opacity, g.Visible, g.OffsetX, g.OffsetY, g.ParallaxX, g.ParallaxY)
+	pending := true
+	for {
+		t, err := s.next()
+		if err != nil {
+			return err
+		}
+		se, ok := t.(xml.StartElement)
+		if !ok {
+			break
+		}
+		switch se.Name.Local {
+		case "properties":
+			err = s.decodeProperties(se, &g.Properties)
+		case "layer", "objectgroup", "imagelayer", "group":
+			if pending && s.h.Group != nil {
+				err = s.h.Group(g, state)
+				if err != nil {
+					return err
+				}
+			}
+			pending = false
+			err = s.decodeNode(se, state)
+		default:
+			err = s.d.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if pending && s.h.Group != nil {
+		return s.h.Group(g, state)
+	}
+	return nil
+}
+
+// decodeData decodes the tiles of the <data> element that starts with start into l.
+func (s *streamer) decodeData(start xml.StartElement, l *Layer) error {
+	var d Data
+	err := decodeAttrs(start, &d)
+	if err != nil {
+		return err
+	}
+	if s.r.selfClosed() {
+		return s.expectEnd()
+	}
+
+	gids, err := s.decodeText(d.Encoding, d.Compression, l.Width*l.Height)
+	if err != nil {
+		return err
+	}
+	for {
+		t, err := s.next()
+		if err != nil {
+			return err
+		}
+		se, ok := t.(xml.StartElement)
+		if !ok {
+			break
+		}
+		switch se.Name.Local {
+		case "tile":
+			var tile SingleTile
+			err = s.d.DecodeElement(&tile, &se)
+			gids = append(gids, tile.GID)
+		case "chunk":
+			var c Chunk
+			c, err = s.decodeChunk(se, d.Encoding, d.Compression)
+			l.Chunks = append(l.Chunks, c)
+		default:
+			err = s.d.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if l.Chunks == nil {
+		l.GIDs = gids
+	}
+	return nil
+}
+
+func (s *streamer) decodeChunk(start xml.StartElement, encoding, compression string) (Chunk, error) {
+	var dc DataChunk
+	err := decodeAttrs(start, &dc)
+	if err != nil {
+		return Chunk{}, err
+	}
+	c := Chunk{X: dc.X, Y: dc.Y, Width: dc.Width, Height: dc.Height}
+	if s.r.selfClosed() {
+		return c, s.expectEnd()
+	}
+
+	c.GIDs, err = s.decodeText(encoding, compression, c.Width*c.Height)
+	if err != nil {
+		return Chunk{}, err
+	}
+	for {
+		t, err := s.next()
+		if err != nil {
+			return Chunk{}, err
+		}
+		se, ok := t.(xml.StartElement)
+		if !ok {
+			break
+		}
+		if se.Name.Local != "tile" {
+			err = s.d.Skip()
+			if err != nil {
+				return Chunk{}, err
+			}
+			continue
+		}
+		var tile SingleTile
+		err = s.d.DecodeElement(&tile, &se)
+		if err != nil {
+			return Chunk{}, err
+		}
+		c.GIDs = append(c.GIDs, tile.GID)
+	}
+	return c, nil
+}
+
+// expectEnd reads the end of a self-closed element.
+func (s *streamer) expectEnd() error {
+	_, err := s.d.Token()
+	return err
+}
+
+// decodeText decodes the text that follows the start of a <data> or <chunk> element,
+// which holds about size tiles. It returns nil if there is no text but space.
+func (s *streamer) decodeText(encoding, compression string, size int) ([]GID, error) {
+	if encoding != "csv" && encoding != "base64" {
+		// As with Decode, data in any other encoding is read from its <tile> elements.
+		return nil, nil
+	}
+	// Space separates the numbers of csv data, but base64 data can't hold it.
+	text := &textReader{s: s, space: encoding == "csv"}
+	blank, err := text.blank()
+	if err != nil || blank {
+		return nil, err
+	}
+	if size < 0 {
+		size = 0
+	}
+	gids := make([]GID, 0, size)
+
+	if encoding == "csv" {
+		return decodeCSV(text, gids)
+	}
+
+	r, err := decompress(base64.NewDecoder(base64.StdEncoding, text), compression)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	br := bufio.NewReader(r)
+	var b [4]byte
+	for {
+		_, err = io.ReadFull(br, b[:])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		gids = append(gids, GID(binary.LittleEndian.Uint32(b[:])))
+	}
+	// Compressed data may end before its text does.
+	_, err = io.Copy(io.Discard, text)
+	if err != nil {
+		return nil, err
+	}
+	return gids, nil
+}
+
+// decodeCSV appends the GIDs of csv text to gids.
+func decodeCSV(text *textReader, gids []GID) ([]GID, error) {
+	var n uint64
+	digits := false
+	for {
+		b, ok, err := text.next()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case !ok:
+			if digits {
+				gids = append(gids, GID(n))
+			}
+			return gids, nil
+		case b >= '0' && b <= '9':
+			n = n*10 + uint64(b-'0')
+			if n > math.MaxUint32 {
+				return nil, fmt.Errorf("tmx: GID out of range in csv data")
+			}
+			digits = true
+			continue
+		case b == ',' || isSpace(b):
+		default:
+			return nil, fmt.Errorf("tmx: malformed csv data: unexpected %q", b)
+		}
+		if digits {
+			gids = append(gids, GID(n))
+			n = 0
+			digits = false
+		}
+	}
+}
+
+// A textReader reads the text of an element up to the next start or end tag.
+// It reads plain text straight from the stream, but once it meets a character reference,
+// CDATA section, comment or processing instruction, it reads the rest of the text as
+// character data from the XML decoder, and leaves the tag that ends it to the streamer.
+type textReader struct {
+	s      *streamer
+	space  bool // Whether to keep space.
+	tokens bool // Whether the text is read from the XML decoder.
+	buf    []byte
+	i      int
+	done   bool
+}
+
+// next returns the next byte of the text, or false at its end.
+func (t *textReader) next() (byte, bool, error) {
+	for !t.done {
+		var b byte
+		if t.tokens {
+			if t.i == len(t.buf) {
+				err := t.fill()
+				if err != nil {
+					return 0, false, err
+				}
+				continue
+			}
+			b = t.buf[t.i]
+			t.i++
+		} else {
+			p, err := t.s.r.r.Peek(2)
+			if len(p) == 0 {
+				return 0, false, unexpectedEOF(err)
+			}
+			if p[0] == '&' || p[0] == '<' && len(p) > 1 && (p[1] == '!' || p[1] == '?') {
+				t.tokens = true
+				continue
+			}
+			if p[0] == '<' {
+				t.done = true
+				continue
+			}
+			b, err = t.s.r.ReadByte()
+			if err != nil {
+				return 0, false, err
+			}
+		}
+		if t.space || !isSpace(b) {
+			return b, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// fill reads the next character data of the text from the XML decoder.
+func (t *textReader) fill() error {
+	tok, err := t.s.d.Token()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	switch tok := tok.(type) {
+	case xml.CharData:
+		// Character data is only good until the next token, which isn't read until it's used up.
+		t.buf, t.i = tok, 0
+	case xml.StartElement:
+		t.s.pending, t.done = tok.Copy(), true
+	case xml.EndElement:
+		t.s.pending, t.done = tok, true
+	}
+	return nil
+}
+
+// unread unreads the last byte returned by next.
+func (t *textReader) unread() error {
+	if t.tokens {
+		t.i--
+		return nil
+	}
+	return t.s.r.unread()
+}
+
+// blank reports whether the text holds nothing but space, which it skips.
+func (t *textReader) blank() (bool, error) {
+	for {
+		b, ok, err := t.next()
+		if err != nil || !ok {
+			return err == nil, err
+		}
+		if !isSpace(b) {
+			return false, t.unread()
+		}
+	}
+}
+
+func (t *textReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		b, ok, err := t.next()
+		if err != nil {
+			return n, err
+		}
+		if !ok {
+			break
+		}
+		p[n] = b
+		n++
+	}
+	if n == 0 && t.done {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,221 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/eq"
+)
+
+type walked struct {
+	n LayerNode
+	s LayerState
+}
+
+// stream returns the map and the layers, in drawing order, that DecodeStream passes to its handler.
+func stream(s string) (*Map, []walked, error) {
+	var m *Map
+	var ls []walked
+	err := DecodeStream(strings.NewReader(s), StreamHandler{
+		Map: func(x *Map) error {
+			m = x
+			return nil
+		},
+		Layer: func(l *Layer, s LayerState) error {
+			ls = append(ls, walked{LayerNode{Layer: l}, s})
+			return nil
+		},
+		ObjectGroup: func(g *ObjectGroup, s LayerState) error {
+			ls = append(ls, walked{LayerNode{ObjectGroup: g}, s})
+			return nil
+		},
+		ImageLayer: func(l *ImageLayer, s LayerState) error {
+			ls = append(ls, walked{LayerNode{ImageLayer: l}, s})
+			return nil
+		},
+		Group: func(g *Group, s LayerState) error {
+			ls = append(ls, walked{LayerNode{Group: g}, s})
+			return nil
+		},
+	})
+	return m, ls, err
+}
+
+// walk returns m without its layers, and the layers of m, in drawing order, with groups left empty.
+func walk(m *Map) (*Map, []walked) {
+	var ls []walked
+	m.Walk(func(n LayerNode, s LayerState) error {
+		if n.Group != nil {
+			g := *n.Group
+			g.Layers = nil
+			n.Group = &g
+		}
+		ls = append(ls, walked{n, s})
+		return nil
+	})
+	x := *m
+	x.LayerTree, x.Layers, x.ObjectGroups, x.ImageLayers, x.Groups = nil, nil, nil, nil, nil
+	return &x, ls
+}
+
+func TestDecodeStream(t *testing.T) {
+	tests := []string{testXml, testCsv, testBase64, testGzip, testZlib, testZstd, testGroups, testTMXFeatures, testParallax, testShapes}
+	for _, data := range []string{testChunksXml, testChunksCsv, testChunksBase64, testChunksZlib, testChunksGzip} {
+		tests = append(tests, strings.Replace(testInfinite, "DATA", data, 1))
+	}
+
+	for i, s := range tests {
+		m, err := Decode(strings.NewReader(s))
+		if err != nil {
+			t.Fatalf("unexpected decode error for %d: %v", i, err)
+		}
+		want, wantLayers := walk(m)
+
+		got, gotLayers, err := stream(s)
+		if err != nil {
+			t.Fatalf("unexpected stream error for %d: %v", i, err)
+		}
+		if !eq.Deep(got, want) {
+			t.Errorf("unequal maps for %d:\n%+v\n------\n%+v", i, got, want)
+		}
+		if !eq.Deep(gotLayers, wantLayers) {
+			t.Errorf("unequal layers for %d:\n%+v\n------\n%+v", i, gotLayers, wantLayers)
+		}
+	}
+}
+
+func TestDecodeStreamObjects(t *testing.T) {
+	m, err := Decode(strings.NewReader(testShapes))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	var objs []Object
+	groups := 0
+	err = DecodeStream(strings.NewReader(testShapes), StreamHandler{
+		Object: func(o *Object, g *ObjectGroup) error {
+			if g.Name != m.ObjectGroups[groups].Name {
+				t.Errorf("object %d in group %q, expected %q", o.ID, g.Name, m.ObjectGroups[groups].Name)
+			}
+			objs = append(objs, *o)
+			return nil
+		},
+		ObjectGroup: func(g *ObjectGroup, s LayerState) error {
+			if g.Objects != nil {
+				t.Errorf("expected no objects in group %q, got %v", g.Name, g.Objects)
+			}
+			groups++
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+
+	var want []Object
+	for _, g := range m.ObjectGroups {
+		want = append(want, g.Objects...)
+	}
+	if !eq.Deep(objs, want) {
+		t.Fatalf("unequal objects:\n%v\n------\n%v", objs, want)
+	}
+}
+
+func TestDecodeStreamEmptyData(t *testing.T) {
+	var names []string
+	err := DecodeStream(strings.NewReader(testEmptyData), StreamHandler{
+		Layer: func(l *Layer, s LayerState) error {
+			if l.GIDs != nil || l.Chunks != nil {
+				t.Errorf("expected no tiles in %q, got %v, %v", l.Name, l.GIDs, l.Chunks)
+			}
+			names = append(names, l.Name)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+	if !eq.Deep(names, []string{"Empty", "Blank"}) {
+		t.Fatalf("got layers %v", names)
+	}
+}
+
+func TestDecodeStreamCharData(t *testing.T) {
+	tests := []string{
+		`<data encoding="csv">1,2,3,4,&#10;5,6,7,8</data>`,
+		`<data encoding="csv">&#49;,2,3,4,5,6,7,8</data>`,
+		`<data encoding="csv"><![CDATA[1,2,3,4,
+5,6,7,8]]></data>`,
+		`<data encoding="csv">1,2,3,4,<!-- row 2 -->5,6,7,8</data>`,
+		`<data encoding="csv">1,2,3,4,<?row 2?>5,6,7,8</data>`,
+		`<data encoding="base64"><![CDATA[AQAAAAIAAAADAAAABAAAAAUAAAAGAAAABwAAAAgAAAA=]]></data>`,
+		`<data encoding="base64">
+   AQAAAAIAAAADAAAABAAAAA&#61;&#61;
+  </data>`,
+		`<data encoding="csv"><![CDATA[ ]]></data>`,
+		`<data encoding="csv">
+   <chunk x="0" y="0" width="2" height="2"><![CDATA[1,2,3,4]]></chunk>
+   <chunk x="2" y="0" width="2" height="2">5,6,&#10;7,8</chunk>
+  </data>`,
+	}
+	for i, data := range tests {
+		s := strings.Replace(testInfinite, "DATA", data, 1)
+		m, err := Decode(strings.NewReader(s))
+		if err != nil {
+			t.Fatalf("unexpected decode error for %d: %v", i, err)
+		}
+		want, wantLayers := walk(m)
+
+		got, gotLayers, err := stream(s)
+		if err != nil {
+			t.Fatalf("unexpected stream error for %d: %v", i, err)
+		}
+		if !eq.Deep(got, want) {
+			t.Errorf("unequal maps for %d:\n%+v\n------\n%+v", i, got, want)
+		}
+		if !eq.Deep(gotLayers, wantLayers) {
+			t.Errorf("unequal layers for %d:\n%+v\n------\n%+v", i, gotLayers, wantLayers)
+		}
+	}
+}
+
+func TestDecodeStreamStop(t *testing.T) {
+	stop := errors.New("stop")
+	n := 0
+	err := DecodeStream(strings.NewReader(testGroups), StreamHandler{
+		Layer: func(l *Layer, s LayerState) error {
+			n++
+			return stop
+		},
+	})
+	if err != stop {
+		t.Fatalf("got error %v, expected %v", err, stop)
+	}
+	if n != 1 {
+		t.Fatalf("got %d layers after stopping", n)
+	}
+}
+
+func TestBadDecodeStream(t *testing.T) {
+	for i, s := range []string{testBadXml, testBadCsv, testBadBase64, testBadZlib, testBadBinary, testBadCompression} {
+		err := DecodeStream(strings.NewReader(s), StreamHandler{})
+		if err == nil {
+			t.Fatalf("expected stream error for %d", i)
+		}
+	}
+}
+
+var testEmptyData = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.2" orientation="orthogonal" width="4" height="2" tilewidth="16" tileheight="16">
+ <layer name="Empty" width="4" height="2">
+  <data encoding="csv"/>
+ </layer>
+ <layer name="Blank" width="4" height="2">
+  <data encoding="base64">
+  </data>
+ </layer>
+</map>
+`
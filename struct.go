@@ -14,9 +14,12 @@ import (
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/xml"
+	"fmt"
 	"io"
 	"strconv"
 	"strings"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 func Decode(r io.Reader) (*Map, error) {
@@ -27,11 +30,9 @@ func Decode(r io.Reader) (*Map, error) {
 		return nil, err
 	}
 
-	for i := range m.Layers {
-		err = m.Layers[i].decodeIDs()
-		if err != nil {
-			return nil, err
-		}
+	err = m.buildLayers()
+	if err != nil {
+		return nil, err
 	}
 
 	return m, nil
@@ -41,37 +42,98 @@ type Map struct {
 	XMLName         xml.Name `xml:"map"`
 	Version         string   `xml:"version,attr"`
 	Orientation     string   `xml:"orientation,attr"`
+	RenderOrder     string   `xml:"renderorder,attr"`
 	Width           int      `xml:"width,attr"`
 	Height          int      `xml:"height,attr"`
 	TileWidth       int      `xml:"tilewidth,attr"`
 	TileHeight      int      `xml:"tileheight,attr"`
+	HexSideLength   int      `xml:"hexsidelength,attr"`
+	StaggerAxis     string   `xml:"staggeraxis,attr"`
+	StaggerIndex    string   `xml:"staggerindex,attr"`
 	BackgroundColor string   `xml:"backgroundcolor,attr"`
+	ParallaxOriginX float64  `xml:"parallaxoriginx,attr"`
+	ParallaxOriginY float64  `xml:"parallaxoriginy,attr"`
+	Class           string   `xml:"class,attr"`
+	Infinite        bool     `xml:"infinite,attr"`
+	NextLayerID     int      `xml:"nextlayerid,attr"`
+	NextObjectID    int      `xml:"nextobjectid,attr"`
+
+	Properties Properties `xml:"properties>property"`
+	Tilesets   []Tileset  `xml:"tileset"`
+
+	// All of the map's layers in drawing order, with group layers nested.
+	// Encoding, Walk and the rest of the package read the map's layers from here,
+	// so this is where to add, remove or reorder them.
+	LayerTree []LayerNode `xml:",any"`
+
+	// Every layer of each kind in drawing order, including those within groups.
+	// Decode fills these in as read-only views of the layers in LayerTree.
+	// They aren't updated when LayerTree changes, and layers added to or removed from
+	// them aren't added to or removed from the map. Only a Map without a LayerTree,
+	// such as one built by hand, has its layers read from here, each kind in turn.
+	Layers       []Layer       `xml:"-"`
+	ObjectGroups []ObjectGroup `xml:"-"`
+	ImageLayers  []ImageLayer  `xml:"-"`
+	Groups       []Group       `xml:"-"`
+}
+
+// Tileset returns the tileset that gid belongs to, or nil if there is none.
+func (m *Map) Tileset(gid GID) *Tileset {
+	gid = gid.ID()
+	var ts *Tileset
+	for i := range m.Tilesets {
+		t := &m.Tilesets[i]
+		if t.FirstGID <= gid && (ts == nil || t.FirstGID > ts.FirstGID) {
+			ts = t
+		}
+	}
+	return ts
+}
 
-	Properties   []Property    `xml:"properties>property"`
-	Tilesets     []Tileset     `xml:"tileset"`
-	Layers       []Layer       `xml:"layer"`
-	ObjectGroups []ObjectGroup `xml:"objectgroup"`
-	ImageLayers  []ImageLayer  `xml:"imagelayer"`
+// Tile returns the tile that gid refers to, or nil if its tileset has no
+// <tile> element for it.
+func (m *Map) Tile(gid GID) *Tile {
+	ts := m.Tileset(gid)
+	if ts == nil {
+		return nil
+	}
+	return ts.Tile(int32(gid.ID() - ts.FirstGID))
 }
 
 type Tileset struct {
-	FirstGID   int32  `xml:"firstgid,attr"`
+	FirstGID   GID    `xml:"firstgid,attr"`
 	Source     string `xml:"source,attr"`
 	Name       string `xml:"name,attr"`
+	Class      string `xml:"class,attr"`
 	TileWidth  int    `xml:"tilewidth,attr"`
 	TileHeight int    `xml:"tileheight,attr"`
 	Spacing    int    `xml:"spacing,attr"`
 	Margin     int    `xml:"margin,attr"`
+	TileCount  int    `xml:"tilecount,attr"`
+	Columns    int    `xml:"columns,attr"`
 
 	TileOffset   TileOffset `xml:"tileoffset"`
-	Properties   []Property `xml:"properties>property"`
+	Properties   Properties `xml:"properties>property"`
 	Image        Image      `xml:"image"`
 	TerrainTypes []Terrain  `xml:"terraintypes>terrain"`
+	Tiles        []Tile     `xml:"tile"`
+	WangSets     []WangSet  `xml:"wangsets>wangset"`
+}
+
+// Tile returns the tile with the given local ID, or nil if the tileset has no
+// <tile> element for it.
+func (t *Tileset) Tile(id int32) *Tile {
+	for i := range t.Tiles {
+		if t.Tiles[i].ID == id {
+			return &t.Tiles[i]
+		}
+	}
+	return nil
 }
 
 type TileOffset struct {
-	X int `xml:"x,attr"`
-	Y int `xml:"y,attr"`
+	X int `xml:"x,attr" json:"x"`
+	Y int `xml:"y,attr" json:"y"`
 }
 
 type Image struct {
@@ -88,134 +150,355 @@ type Terrain struct {
 	Name string `xml:"name,attr"`
 	Tile int    `xml:"tile,attr"`
 
-	Properties []Property `xml:"properties>property"`
+	Properties Properties `xml:"properties>property"`
 }
 
 type Tile struct {
 	ID          int32   `xml:"id,attr"`
+	Type        string  `xml:"type,attr"`
 	Terrain     string  `xml:"terrain,attr"`
 	Probability float32 `xml:"probability,attr"`
 
-	Properties []Property `xml:"properties>property"`
+	// The sub-rectangle of Image used by a tile of an image collection.
+	// A zero Width and Height mean the whole image.
+	X      int `xml:"x,attr"`
+	Y      int `xml:"y,attr"`
+	Width  int `xml:"width,attr"`
+	Height int `xml:"height,attr"`
+
+	Properties Properties `xml:"properties>property"`
 	Image      Image      `xml:"image"`
+	Animation  []Frame    `xml:"animation>frame"`
 }
 
-type Layer struct {
-	Name    string  `xml:"name,attr"`
-	Opacity float32 `xml:"opacity,attr"`
-	Visible bool    `xml:"visible,attr"`
+type Frame struct {
+	TileID   int32 `xml:"tileid,attr"`
+	Duration int   `xml:"duration,attr"` // In milliseconds.
+}
 
-	Properties []Property `xml:"properties>property"`
+type Layer struct {
+	ID        int     `xml:"id,attr"`
+	Name      string  `xml:"name,attr"`
+	Class     string  `xml:"class,attr"`
+	Width     int     `xml:"width,attr"`
+	Height    int     `xml:"height,attr"`
+	Opacity   float32 `xml:"opacity,attr"`
+	Visible   bool    `xml:"visible,attr"`
+	TintColor string  `xml:"tintcolor,attr"`
+	OffsetX   float64 `xml:"offsetx,attr"`
+	OffsetY   float64 `xml:"offsety,attr"`
+	ParallaxX float64 `xml:"parallaxx,attr"`
+	ParallaxY float64 `xml:"parallaxy,attr"`
+	Locked    bool    `xml:"locked,attr"`
+
+	Properties Properties `xml:"properties>property"`
 	Data       Data       `xml:"data"`
 
 	// The GID of each tile, in order. Use this instead of raw Data, which is cleaned up by Decode.
-	GIDs []int32 `xml:"-"`
+	GIDs []GID `xml:"-"`
+
+	// The chunks of an infinite map's layer, in place of GIDs.
+	Chunks []Chunk `xml:"-"`
+}
+
+// A Chunk is a rectangular piece of an infinite map's layer.
+type Chunk struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+
+	// The GID of each tile in the chunk, in order.
+	GIDs []GID
+}
+
+// GIDAt returns the GID of the tile at x, y, in tiles. Coordinates may be
+// negative in infinite maps. It returns 0 for positions with no tile data.
+func (l *Layer) GIDAt(x, y int) GID {
+	if l.Chunks == nil {
+		return gidAt(l.GIDs, 0, 0, l.Width, l.Height, x, y)
+	}
+	for i := range l.Chunks {
+		c := &l.Chunks[i]
+		if x >= c.X && x < c.X+c.Width && y >= c.Y && y < c.Y+c.Height {
+			return gidAt(c.GIDs, c.X, c.Y, c.Width, c.Height, x, y)
+		}
+	}
+	return 0
+}
+
+func gidAt(gids []GID, x0, y0, w, h, x, y int) GID {
+	x -= x0
+	y -= y0
+	if x < 0 || x >= w || y < 0 || y >= h {
+		return 0
+	}
+	i := y*w + x
+	if i >= len(gids) {
+		return 0
+	}
+	return gids[i]
 }
 
 func (l *Layer) decodeIDs() error {
 	d := &l.Data
-	if d.Encoding == "csv" {
-		r := bufio.NewScanner(strings.NewReader(d.Text))
+	if d.empty() {
+		// Nothing to decode, as for layers built from JSON.
+		return nil
+	}
+	if len(d.Chunks) == 0 {
+		gids, err := decodeGIDs(d.Encoding, d.Compression, d.Text, d.Tiles)
+		if err != nil {
+			return err
+		}
+		l.GIDs = gids
+	}
+
+	for i := range d.Chunks {
+		c := &d.Chunks[i]
+		gids, err := decodeGIDs(d.Encoding, d.Compression, c.Text, c.Tiles)
+		if err != nil {
+			return err
+		}
+		l.Chunks = append(l.Chunks, Chunk{
+			X:      c.X,
+			Y:      c.Y,
+			Width:  c.Width,
+			Height: c.Height,
+			GIDs:   gids,
+		})
+	}
+
+	l.Data = Data{}
+	return nil
+}
+
+func decodeGIDs(encoding, compression, text string, tiles []SingleTile) ([]GID, error) {
+	var gids []GID
+	if encoding == "csv" {
+		r := bufio.NewScanner(strings.NewReader(text))
 		for r.Scan() {
 			line := r.Text()
 			parts := strings.Split(line, ",")
 			for _, p := range parts {
+				p = strings.TrimSpace(p)
 				if p == "" {
 					continue
 				}
-				n, err := strconv.ParseInt(p, 10, 32)
+				n, err := strconv.ParseUint(p, 10, 32)
 				if err != nil {
-					return err
+					return nil, err
 				}
-				l.GIDs = append(l.GIDs, int32(n))
+				gids = append(gids, GID(n))
 			}
 		}
-	} else if d.Encoding == "base64" {
-		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(d.Text))
+	} else if encoding == "base64" {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
 		if err != nil {
-			return err
-		}
-		var r io.Reader = bytes.NewReader(raw)
-		if d.Compression == "zlib" {
-			r, err = zlib.NewReader(r)
-		} else if d.Compression == "gzip" {
-			r, err = gzip.NewReader(r)
+			return nil, err
 		}
+		r, err := decompress(bytes.NewReader(raw), compression)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		defer r.Close()
 		for {
-			var n int32
+			var n GID
 			err = binary.Read(r, binary.LittleEndian, &n)
 			if err == io.EOF {
 				break
 			}
 			if err != nil {
-				return err
+				return nil, err
 			}
-			l.GIDs = append(l.GIDs, n)
+			gids = append(gids, n)
 		}
 	} else {
-		for i := range d.Tiles {
-			l.GIDs = append(l.GIDs, d.Tiles[i].GID)
+		for i := range tiles {
+			gids = append(gids, tiles[i].GID)
 		}
 	}
+	return gids, nil
+}
 
-	l.Data = Data{}
-	return nil
+// decompress returns a reader of the data in r, decompressed as named by compression.
+func decompress(r io.Reader, compression string) (io.ReadCloser, error) {
+	switch compression {
+	case "":
+		return io.NopCloser(r), nil
+	case "zlib":
+		return zlib.NewReader(r)
+	case "gzip":
+		return gzip.NewReader(r)
+	case "zstd":
+		z, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return z.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("tmx: unknown compression %q", compression)
 }
 
 type Data struct {
 	Encoding    string `xml:"encoding,attr"`
 	Compression string `xml:"compression,attr"`
 
+	Text   string       `xml:",chardata"`
+	Tiles  []SingleTile `xml:"tile"`
+	Chunks []DataChunk  `xml:"chunk"`
+}
+
+func (d *Data) empty() bool {
+	return d.Encoding == "" && d.Compression == "" && d.Text == "" && d.Tiles == nil && d.Chunks == nil
+}
+
+type DataChunk struct {
+	X      int `xml:"x,attr"`
+	Y      int `xml:"y,attr"`
+	Width  int `xml:"width,attr"`
+	Height int `xml:"height,attr"`
+
 	Text  string       `xml:",chardata"`
 	Tiles []SingleTile `xml:"tile"`
 }
 
 type SingleTile struct {
-	GID int32 `xml:"gid,attr"`
+	GID GID `xml:"gid,attr"`
 }
 
 type ObjectGroup struct {
-	Name    string  `xml:"name,attr"`
-	Color   string  `xml:"color,attr"`
-	Opacity float32 `xml:"opacity,attr"`
-	Visible bool    `xml:"visible,attr"`
-
-	Properties []Property `xml:"properties>property"`
+	ID        int     `xml:"id,attr"`
+	Name      string  `xml:"name,attr"`
+	Class     string  `xml:"class,attr"`
+	Color     string  `xml:"color,attr"`
+	Opacity   float32 `xml:"opacity,attr"`
+	Visible   bool    `xml:"visible,attr"`
+	TintColor string  `xml:"tintcolor,attr"`
+	OffsetX   float64 `xml:"offsetx,attr"`
+	OffsetY   float64 `xml:"offsety,attr"`
+	ParallaxX float64 `xml:"parallaxx,attr"`
+	ParallaxY float64 `xml:"parallaxy,attr"`
+	Locked    bool    `xml:"locked,attr"`
+
+	Properties Properties `xml:"properties>property"`
 	Objects    []Object   `xml:"object"`
 }
 
 type Object struct {
+	ID       int     `xml:"id,attr"`
 	Name     string  `xml:"name,attr"`
 	Type     string  `xml:"type,attr"`
-	X        int     `xml:"x,attr"`
-	Y        int     `xml:"y,attr"`
-	Width    int     `xml:"width,attr"`
-	Height   int     `xml:"height,attr"`
-	Rotation float32 `xml:"rotation,attr"`
-	GID      int32   `xml:"gid,attr"`
+	X        float64 `xml:"x,attr"`
+	Y        float64 `xml:"y,attr"`
+	Width    float64 `xml:"width,attr"`
+	Height   float64 `xml:"height,attr"`
+	Rotation float64 `xml:"rotation,attr"`
+	GID      GID     `xml:"gid,attr"`
 	Visible  bool    `xml:"visible,attr"`
+	Template string  `xml:"template,attr"`
+
+	Properties Properties   `xml:"properties>property"`
+	Ellipse    *Ellipse     `xml:"ellipse"`
+	Point      *PointMarker `xml:"point"`
+	Polygon    Points       `xml:"polygon"`
+	Polylines  Points       `xml:"polyline"`
+	Text       *Text        `xml:"text"`
+
+	// What a template instance sets itself, as decoded.
+	instance *instance
+}
 
-	Properties []Property `xml:"properties>property"`
-	Ellipse    *Ellipse   `xml:"ellipse"`
-	Polygon    string     `xml:"polygon>points"`
-	Polylines  string     `xml:"polyline>points"`
+// An instance holds what a template instance sets itself, which is all of it that Encode writes
+// besides what now differs from the template.
+type instance struct {
+	attrs      []xml.Attr
+	properties Properties
+
+	// The template's object, once applied.
+	template *Object
+}
+
+// sets reports whether the instance sets the attribute name itself.
+func (in *instance) sets(name string) bool {
+	if in == nil {
+		return false
+	}
+	for _, a := range in.attrs {
+		if a.Name.Local == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Object) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type object Object
+	x := struct {
+		object
+		// Tiled 1.9 wrote an object's type as its class.
+		Class string `xml:"class,attr"`
+	}{object: object{Visible: true}}
+	err := d.DecodeElement(&x, &start)
+	if err != nil {
+		return err
+	}
+	*o = Object(x.object)
+	class := o.Type == "" && x.Class != ""
+	if class {
+		o.Type = x.Class
+	}
+	if o.Template != "" {
+		attrs := append([]xml.Attr(nil), start.Attr...)
+		for i := range attrs {
+			if class && attrs[i].Name.Local == "class" {
+				attrs[i].Name.Local = "type"
+			}
+		}
+		o.instance = &instance{attrs: attrs, properties: o.Properties}
+	}
+	return nil
 }
 
 type Ellipse struct{}
 
-type ImageLayer struct {
-	Name    string  `xml:"name,attr"`
-	Opacity float32 `xml:"opacity,attr"`
-	Visible bool    `xml:"visible,attr"`
+type PointMarker struct{}
 
-	Properties []Property `xml:"properties>property"`
+type ImageLayer struct {
+	ID        int     `xml:"id,attr"`
+	Name      string  `xml:"name,attr"`
+	Class     string  `xml:"class,attr"`
+	Opacity   float32 `xml:"opacity,attr"`
+	Visible   bool    `xml:"visible,attr"`
+	TintColor string  `xml:"tintcolor,attr"`
+	OffsetX   float64 `xml:"offsetx,attr"`
+	OffsetY   float64 `xml:"offsety,attr"`
+	ParallaxX float64 `xml:"parallaxx,attr"`
+	ParallaxY float64 `xml:"parallaxy,attr"`
+	Locked    bool    `xml:"locked,attr"`
+
+	Properties Properties `xml:"properties>property"`
 	Image      Image      `xml:"image"`
 }
 
 type Property struct {
-	Name  string `xml:"name,attr"`
+	Name string `xml:"name,attr"`
+
+	// One of string, int, float, bool, color, file, object or class.
+	// An empty Type means string.
+	Type string `xml:"type,attr"`
+
+	// The name of the custom type of a class or enum property.
+	PropertyType string `xml:"propertytype,attr"`
+
+	// The value of any property but a class. Multiline strings are
+	// stored as the element's text rather than in the attribute.
 	Value string `xml:"value,attr"`
+
+	// The members of a class property that differ from their defaults,
+	// or all of its members once a Project is applied.
+	Properties Properties `xml:"properties>property"`
+
+	// The names of the values of an enum property, filled in by Project.Apply.
+	EnumValues []string `xml:"-"`
 }
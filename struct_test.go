@@ -12,7 +12,7 @@ import (
 func TestDecode(t *testing.T) {
 	var maps []*Map
 
-	for i, s := range []string{ testXml, testCsv, testBase64, testGzip, testZlib } {
+	for i, s := range []string{ testXml, testCsv, testBase64, testGzip, testZlib, testZstd } {
 		m, err := Decode(strings.NewReader(s))
 		if err != nil {
 			t.Fatalf("unexpected decode error for %d: %v", i, err)
@@ -28,7 +28,7 @@ func TestDecode(t *testing.T) {
 }
 
 func TestBadDecode(t *testing.T) {
-	for i, s := range []string{ testBadXml, testBadCsv, testBadBase64, testBadZlib, testBadBinary } {
+	for i, s := range []string{ testBadXml, testBadCsv, testBadBase64, testBadZlib, testBadBinary, testBadCompression } {
 		_, err := Decode(strings.NewReader(s))
 		if err == nil {
 			t.Fatalf("expected decode error for %d, got: %v", i, err)
@@ -36,6 +36,63 @@ func TestBadDecode(t *testing.T) {
 	}
 }
 
+func TestTiles(t *testing.T) {
+	m, err := Decode(strings.NewReader(testTiles))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	ts := &m.Tilesets[1]
+	if len(ts.Tiles) != 2 {
+		t.Fatalf("expected 2 tiles, got %d", len(ts.Tiles))
+	}
+	if m.Tileset(12) != ts || m.Tileset(10) != &m.Tilesets[0] || m.Tileset(0) != nil {
+		t.Fatalf("wrong tileset lookup")
+	}
+
+	tile := m.Tile(14)
+	if tile == nil || tile.ID != 3 {
+		t.Fatalf("expected tile 3, got %v", tile)
+	}
+	if tile.Probability != 0.5 || tile.Image.Source != "lava.png" {
+		t.Fatalf("wrong tile attributes: %v", tile)
+	}
+	if len(tile.Properties) != 1 || !eq.Deep(tile.Properties[0], Property{Name: "damage", Value: "10"}) {
+		t.Fatalf("wrong tile properties: %v", tile.Properties)
+	}
+	if ts.Tile(1) != nil || m.Tile(1) != nil {
+		t.Fatalf("expected no tile for local ID 1")
+	}
+}
+
+var testTiles = `
+<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.0" orientation="orthogonal" width="2" height="1" tilewidth="16" tileheight="16">
+ <tileset firstgid="1" name="land" tilewidth="16" tileheight="16" tilecount="3" columns="3">
+  <image source="tiles.png" width="48" height="16"/>
+ </tileset>
+ <tileset firstgid="11" name="hazards" tilewidth="16" tileheight="16" tilecount="4" columns="4">
+  <image source="hazards.png" width="64" height="16"/>
+  <tile id="0">
+   <properties>
+    <property name="solid" value="true"/>
+   </properties>
+  </tile>
+  <tile id="3" probability="0.5">
+   <properties>
+    <property name="damage" value="10"/>
+   </properties>
+   <image source="lava.png" width="16" height="16"/>
+  </tile>
+ </tileset>
+ <layer name="Ground" width="2" height="1">
+  <data encoding="csv">
+11,14
+</data>
+ </layer>
+</map>
+`
+
 var testXml = `
 <?xml version="1.0" encoding="UTF-8"?>
 <map version="1.0" orientation="orthogonal" width="10" height="10" tilewidth="16" tileheight="16">
@@ -468,6 +525,60 @@ var testZlib = `
 </map>
 `
 
+var testZstd = `
+<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.0" orientation="orthogonal" width="10" height="10" tilewidth="16" tileheight="16">
+ <tileset firstgid="1" name="land" tilewidth="16" tileheight="16">
+  <image source="tiles.png" width="48" height="16"/>
+ </tileset>
+ <layer name="Foreground" width="10" height="10">
+  <data encoding="base64" compression="zstd">
+   KLUv/UQAkABVAgBSAQKCMRAPVSh4LyKAkL0HLwhgAPkmAcTDcIAFwVPcoAB2hxCYAhMYCCBAAPgVyFFyCGJiKPAexQm6ILvLfxJf0Stv5nsUog/dDwwaya0=
+  </data>
+ </layer>
+ <objectgroup name="Mountains" width="10" height="10">
+  <object type="mountain" x="48" y="48" width="16" height="16"/>
+  <object type="mountain" x="64" y="32" width="16" height="16"/>
+  <object type="mountain" x="80" y="32" width="16" height="16"/>
+  <object type="mountain" x="64" y="48" width="16" height="16"/>
+  <object type="mountain" x="96" y="48" width="16" height="16"/>
+  <object type="mountain" x="80" y="48" width="16" height="16"/>
+  <object type="mountain" x="64" y="64" width="16" height="16"/>
+  <object type="mountain" x="80" y="64" width="16" height="16"/>
+  <object type="mountain" x="96" y="64" width="16" height="16"/>
+  <object type="mountain" x="80" y="80" width="16" height="16"/>
+  <object type="mountain" x="96" y="80" width="16" height="16"/>
+ </objectgroup>
+</map>
+`
+
+var testBadCompression = `
+<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.0" orientation="orthogonal" width="10" height="10" tilewidth="16" tileheight="16">
+ <tileset firstgid="1" name="land" tilewidth="16" tileheight="16">
+  <image source="tiles.png" width="48" height="16"/>
+ </tileset>
+ <layer name="Foreground" width="10" height="10">
+  <data encoding="base64" compression="lzma">
+   eJxjZmBgYAZiRjyYGU0NMxrGp4cJinGpRVaDrJaQeYTUoduNTx02/+DzLy51xIQZoXAGAJlEAMI=
+  </data>
+ </layer>
+ <objectgroup name="Mountains" width="10" height="10">
+  <object type="mountain" x="48" y="48" width="16" height="16"/>
+  <object type="mountain" x="64" y="32" width="16" height="16"/>
+  <object type="mountain" x="80" y="32" width="16" height="16"/>
+  <object type="mountain" x="64" y="48" width="16" height="16"/>
+  <object type="mountain" x="96" y="48" width="16" height="16"/>
+  <object type="mountain" x="80" y="48" width="16" height="16"/>
+  <object type="mountain" x="64" y="64" width="16" height="16"/>
+  <object type="mountain" x="80" y="64" width="16" height="16"/>
+  <object type="mountain" x="96" y="64" width="16" height="16"/>
+  <object type="mountain" x="80" y="80" width="16" height="16"/>
+  <object type="mountain" x="96" y="80" width="16" height="16"/>
+ </objectgroup>
+</map>
+`
+
 var testBadZlib = `
 <?xml version="1.0" encoding="UTF-8"?>
 <map version="1.0" orientation="orthogonal" width="10" height="10" tilewidth="16" tileheight="16">
@@ -0,0 +1,242 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+)
+
+// A Template is an object template (TX) file.
+type Template struct {
+	XMLName xml.Name `xml:"template"`
+	Tileset *Tileset `xml:"tileset"`
+	Object  Object   `xml:"object"`
+}
+
+// DecodeTemplate decodes an object template (TX) file.
+func DecodeTemplate(r io.Reader) (*Template, error) {
+	t := new(Template)
+	err := xml.NewDecoder(r).Decode(t)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// loadTemplate loads the template source, referenced by the file from, in either
+// TX or JSON format, along with its external tileset, if any.
+func loadTemplate(from, source string, res Resolver) (*Template, error) {
+	name := resolvePath(from, source)
+	f, err := res.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("tmx: %s: template %s: %w", from, source, err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var t *Template
+	if isJSON(r) {
+		t, err = DecodeJSONTemplate(r)
+	} else {
+		t, err = DecodeTemplate(r)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tmx: %s: %w", name, err)
+	}
+	if t.Tileset != nil {
+		err = t.Tileset.load(name, res)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// applyTemplates replaces each template instance in m, which was loaded from the file name,
+// with its template, overridden by the instance's own attributes, properties and shape.
+func (m *Map) applyTemplates(name string, res Resolver) error {
+	templates := make(map[string]*Template)
+	return m.Walk(func(n LayerNode, s LayerState) error {
+		g := n.ObjectGroup
+		if g == nil {
+			return nil
+		}
+		for j := range g.Objects {
+			o := &g.Objects[j]
+			if o.Template == "" {
+				continue
+			}
+
+			tname := resolvePath(name, o.Template)
+			t, ok := templates[tname]
+			if !ok {
+				var err error
+				t, err = loadTemplate(name, o.Template, res)
+				if err != nil {
+					return err
+				}
+				if t.Object.GID != 0 && t.Tileset != nil {
+					// The template's tileset is added to the map once, for all of its instances.
+					ts := m.templateTileset(t.Tileset, name, tname)
+					t.Object.GID = ts.FirstGID + t.Object.GID.ID() - t.Tileset.FirstGID | t.Object.GID.Flags()
+				}
+				templates[tname] = t
+			}
+
+			err := applyTemplate(o, t)
+			if err != nil {
+				return fmt.Errorf("tmx: %s: object %d: %w", name, o.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+func applyTemplate(o *Object, t *Template) error {
+	in := o.instance
+	if in == nil {
+		in = new(instance)
+	}
+	x := t.Object.clone()
+
+	// Decoding a bare element with the instance's attributes onto the template's object
+	// overrides exactly the attributes that the instance sets.
+	type object Object
+	toks := &tokens{
+		xml.StartElement{Name: xml.Name{Local: "object"}, Attr: in.attrs},
+		xml.EndElement{Name: xml.Name{Local: "object"}},
+	}
+	err := xml.NewTokenDecoder(toks).Decode((*object)(&x))
+	if err != nil {
+		return err
+	}
+
+	x.Properties = mergeProperties(x.Properties, o.Properties)
+	if o.Ellipse != nil || o.Point != nil || o.Polygon != nil || o.Polylines != nil || o.Text != nil {
+		x.Ellipse = o.Ellipse
+		x.Point = o.Point
+		x.Polygon = o.Polygon
+		x.Polylines = o.Polylines
+		x.Text = o.Text
+	}
+	x.Template = o.Template
+	x.instance = &instance{attrs: in.attrs, properties: in.properties, template: &t.Object}
+	*o = x
+	return nil
+}
+
+// templateTileset returns the map's tileset for the tileset of a template,
+// adding it to the map if the map doesn't use it yet.
+func (m *Map) templateTileset(ts *Tileset, name, tname string) *Tileset {
+	if ts.Source != "" {
+		src := resolvePath(tname, ts.Source)
+		for i := range m.Tilesets {
+			if m.Tilesets[i].Source != "" && resolvePath(name, m.Tilesets[i].Source) == src {
+				return &m.Tilesets[i]
+			}
+		}
+	}
+
+	next := GID(1)
+	for i := range m.Tilesets {
+		if end := m.Tilesets[i].FirstGID + m.Tilesets[i].gidCount(); end > next {
+			next = end
+		}
+	}
+	added := *ts
+	added.FirstGID = next
+	if ts.Source != "" {
+		added.Source = relativePath(path.Dir(name), resolvePath(tname, ts.Source))
+	}
+	m.Tilesets = append(m.Tilesets, added)
+	return &m.Tilesets[len(m.Tilesets)-1]
+}
+
+// gidCount returns the number of GIDs that t takes up in a map.
+func (t *Tileset) gidCount() GID {
+	n := GID(t.TileCount)
+	if n == 0 && t.TileWidth > 0 && t.TileHeight > 0 {
+		// Tilesets saved by older versions of Tiled have no tilecount.
+		cols := (t.Image.Width - 2*t.Margin + t.Spacing) / (t.TileWidth + t.Spacing)
+		rows := (t.Image.Height - 2*t.Margin + t.Spacing) / (t.TileHeight + t.Spacing)
+		if cols > 0 && rows > 0 {
+			n = GID(cols * rows)
+		}
+	}
+	for i := range t.Tiles {
+		if id := GID(t.Tiles[i].ID) + 1; id > n {
+			n = id
+		}
+	}
+	return n
+}
+
+// relativePath returns the slash-separated path of target relative to the directory dir.
+func relativePath(dir, target string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(dir), filepath.FromSlash(target))
+	if err != nil {
+		return target
+	}
+	return filepath.ToSlash(rel)
+}
+
+// clone returns a copy of o that shares no memory with it.
+func (o *Object) clone() Object {
+	x := *o
+	if o.Properties != nil {
+		x.Properties = append(Properties{}, o.Properties...)
+	}
+	if o.Polygon != nil {
+		x.Polygon = append(Points{}, o.Polygon...)
+	}
+	if o.Polylines != nil {
+		x.Polylines = append(Points{}, o.Polylines...)
+	}
+	if o.Ellipse != nil {
+		x.Ellipse = new(Ellipse)
+	}
+	if o.Point != nil {
+		x.Point = new(PointMarker)
+	}
+	if o.Text != nil {
+		text := *o.Text
+		x.Text = &text
+	}
+	return x
+}
+
+// mergeProperties returns base with each of the overrides replacing the property of the same name,
+// or appended if base has none.
+func mergeProperties(base, overrides Properties) Properties {
+	for _, p := range overrides {
+		found := false
+		for i := range base {
+			if base[i].Name == p.Name {
+				base[i] = p
+				found = true
+				break
+			}
+		}
+		if !found {
+			base = append(base, p)
+		}
+	}
+	return base
+}
+
+// tokens is an xml.TokenReader over a fixed list of tokens.
+type tokens []xml.Token
+
+func (ts *tokens) Token() (xml.Token, error) {
+	if len(*ts) == 0 {
+		return nil, io.EOF
+	}
+	t := (*ts)[0]
+	*ts = (*ts)[1:]
+	return t, nil
+}
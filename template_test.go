@@ -0,0 +1,184 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/eaburns/eq"
+)
+
+func TestTemplates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"maps/level.tmx":         {Data: []byte(testTemplateMap)},
+		"tilesets/land.tsx":      {Data: []byte(testTsx)},
+		"tilesets/monsters.tsx":  {Data: []byte(testMonstersTsx)},
+		"templates/enemy.tx":     {Data: []byte(testEnemyTx)},
+		"templates/sign.tx":      {Data: []byte(testSignTx)},
+		"templates/boss/boss.tx": {Data: []byte(testBossTx)},
+	}
+
+	m, err := DecodeFile("maps/level.tmx", FS(fsys))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	if len(m.Tilesets) != 2 {
+		t.Fatalf("expected the boss's tileset to be added, got %d tilesets", len(m.Tilesets))
+	}
+	added := m.Tilesets[1]
+	if added.FirstGID != 4 || added.Source != "../tilesets/monsters.tsx" || added.Name != "monsters" {
+		t.Fatalf("wrong added tileset: %v", added)
+	}
+
+	objs := withoutInstances(m.ObjectGroups[0].Objects)
+	want := []Object{
+		{
+			ID: 1, Name: "grunt", Type: "enemy", X: 32, Y: 48, Width: 16, Height: 16, Visible: true,
+			GID: 2, Template: "../templates/enemy.tx",
+			Properties: Properties{{Name: "hp", Value: "5"}, {Name: "speed", Value: "2"}},
+		},
+		{
+			ID: 2, Name: "captain", Type: "enemy", X: 64, Y: 48, Width: 32, Height: 32, Visible: true,
+			GID: 3 | FlipHorizontal, Template: "../templates/enemy.tx",
+			Properties: Properties{{Name: "hp", Value: "20"}, {Name: "speed", Value: "2"}, {Name: "armor", Value: "3"}},
+		},
+		{
+			ID: 3, Name: "sign", X: 8, Y: 8, Width: 64, Height: 16, Visible: true, Template: "../templates/sign.tx",
+			Text: &Text{FontFamily: "sans-serif", PixelSize: 16, Color: "#000000", Kerning: true, HAlign: "left", VAlign: "top", Contents: "Keep out"},
+		},
+		{
+			ID: 4, Name: "boss", Type: "enemy", X: 100, Y: 100, Width: 48, Height: 48, Visible: true,
+			GID: 5, Template: "../templates/boss/boss.tx",
+		},
+	}
+	if !eq.Deep(objs, want) {
+		t.Fatalf("unequal:\n%+v\n------\n%+v", objs, want)
+	}
+}
+
+// withoutInstances returns a copy of objs without what each template instance sets itself,
+// which depends on how the instance was written.
+func withoutInstances(objs []Object) []Object {
+	objs = append([]Object(nil), objs...)
+	for i := range objs {
+		objs[i].instance = nil
+	}
+	return objs
+}
+
+func TestEmbeddedTilesetTemplate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"maps/level.tmx":     {Data: []byte(testCrateMap)},
+		"templates/crate.tx": {Data: []byte(testCrateTx)},
+	}
+
+	m, err := DecodeFile("maps/level.tmx", FS(fsys))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if len(m.Tilesets) != 2 {
+		t.Fatalf("expected the crate's tileset to be added once, got %d tilesets", len(m.Tilesets))
+	}
+	if ts := m.Tilesets[1]; ts.FirstGID != 4 || ts.Name != "crates" {
+		t.Fatalf("wrong added tileset: %+v", ts)
+	}
+	for _, o := range m.ObjectGroups[0].Objects {
+		if o.GID != 5 {
+			t.Errorf("object %d has GID %d, expected 5", o.ID, o.GID)
+		}
+	}
+}
+
+func TestMissingTemplate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"maps/level.tmx":    {Data: []byte(testTemplateMap)},
+		"tilesets/land.tsx": {Data: []byte(testTsx)},
+	}
+
+	_, err := DecodeFile("maps/level.tmx", FS(fsys))
+	if err == nil {
+		t.Fatalf("expected error for missing templates")
+	}
+	if !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), "maps/level.tmx: template ../templates/enemy.tx:") {
+		t.Fatalf("wrong error for missing template: %v", err)
+	}
+}
+
+var testTemplateMap = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.2" orientation="orthogonal" width="10" height="10" tilewidth="16" tileheight="16" nextobjectid="5">
+ <tileset firstgid="1" source="../tilesets/land.tsx"/>
+ <objectgroup id="1" name="Actors">
+  <object id="1" template="../templates/enemy.tx" x="32" y="48"/>
+  <object id="2" template="../templates/enemy.tx" name="captain" gid="2147483651" x="64" y="48" width="32" height="32">
+   <properties>
+    <property name="hp" value="20"/>
+    <property name="armor" value="3"/>
+   </properties>
+  </object>
+  <object id="3" template="../templates/sign.tx" x="8" y="8">
+   <text>Keep out</text>
+  </object>
+  <object id="4" template="../templates/boss/boss.tx" x="100" y="100"/>
+ </objectgroup>
+</map>
+`
+
+var testEnemyTx = `<?xml version="1.0" encoding="UTF-8"?>
+<template>
+ <tileset firstgid="1" source="../tilesets/land.tsx"/>
+ <object name="grunt" type="enemy" gid="2" width="16" height="16">
+  <properties>
+   <property name="hp" value="5"/>
+   <property name="speed" value="2"/>
+  </properties>
+ </object>
+</template>
+`
+
+var testSignTx = `<?xml version="1.0" encoding="UTF-8"?>
+<template>
+ <object name="sign" width="64" height="16">
+  <text>Welcome</text>
+ </object>
+</template>
+`
+
+var testBossTx = `<?xml version="1.0" encoding="UTF-8"?>
+<template>
+ <tileset firstgid="1" source="../../tilesets/monsters.tsx"/>
+ <object name="boss" type="enemy" gid="2" width="48" height="48"/>
+</template>
+`
+
+var testMonstersTsx = `<?xml version="1.0" encoding="UTF-8"?>
+<tileset name="monsters" tilewidth="48" tileheight="48" tilecount="4" columns="4">
+ <image source="monsters.png" width="192" height="48"/>
+</tileset>
+`
+
+var testCrateMap = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.2" orientation="orthogonal" width="10" height="10" tilewidth="16" tileheight="16" nextobjectid="4">
+ <tileset firstgid="1" name="land" tilewidth="16" tileheight="16" tilecount="3" columns="3">
+  <image source="land.png" width="48" height="16"/>
+ </tileset>
+ <objectgroup id="1" name="Crates">
+  <object id="1" template="../templates/crate.tx" x="0" y="16"/>
+  <object id="2" template="../templates/crate.tx" x="16" y="16"/>
+  <object id="3" template="../templates/crate.tx" x="32" y="16"/>
+ </objectgroup>
+</map>
+`
+
+var testCrateTx = `<?xml version="1.0" encoding="UTF-8"?>
+<template>
+ <tileset firstgid="1" name="crates" tilewidth="16" tileheight="16" tilecount="2" columns="2">
+  <image source="crates.png" width="32" height="16"/>
+ </tileset>
+ <object name="crate" gid="2" width="16" height="16"/>
+</template>
+`
@@ -0,0 +1,177 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type WangSet struct {
+	Name  string `xml:"name,attr"`
+	Class string `xml:"class,attr"`
+	Type  string `xml:"type,attr"` // One of corner, edge or mixed.
+	Tile  int32  `xml:"tile,attr"` // The local ID of the tile representing the set, or -1.
+
+	Properties Properties  `xml:"properties>property"`
+	Colors     []WangColor `xml:"wangcolor"`
+	Tiles      []WangTile  `xml:"wangtile"`
+}
+
+// UnmarshalXML also decodes the separate edge and corner colors written by Tiled before
+// version 1.5. As when Tiled loads them, the corner colors follow the edge colors in Colors,
+// and the corner color indexes of the set's Wang IDs are moved past the edge colors.
+func (w *WangSet) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type wangSet WangSet
+	var x struct {
+		wangSet
+		EdgeColors   []WangColor `xml:"wangedgecolor"`
+		CornerColors []WangColor `xml:"wangcornercolor"`
+	}
+	err := d.DecodeElement(&x, &start)
+	if err != nil {
+		return err
+	}
+	*w = WangSet(x.wangSet)
+	if x.EdgeColors == nil && x.CornerColors == nil {
+		return nil
+	}
+
+	w.Colors = append(append(w.Colors, x.EdgeColors...), x.CornerColors...)
+	if w.Type == "" {
+		switch {
+		case x.CornerColors == nil:
+			w.Type = "edge"
+		case x.EdgeColors == nil:
+			w.Type = "corner"
+		default:
+			w.Type = "mixed"
+		}
+	}
+	edges := uint8(len(x.EdgeColors))
+	for i := range w.Tiles {
+		id := &w.Tiles[i].WangID
+		for j := 1; j < len(id); j += 2 {
+			if id[j] != 0 {
+				id[j] += edges
+			}
+		}
+	}
+	return nil
+}
+
+// WangID returns the Wang ID of the tile with the given local ID,
+// and whether the tile is part of the set.
+func (w *WangSet) WangID(tile int32) (WangID, bool) {
+	for i := range w.Tiles {
+		if w.Tiles[i].TileID == tile {
+			return w.Tiles[i].WangID, true
+		}
+	}
+	return WangID{}, false
+}
+
+// Color returns the color with the given index, as used in WangIDs, or nil for 0 or an unknown index.
+func (w *WangSet) Color(index int) *WangColor {
+	if index < 1 || index > len(w.Colors) {
+		return nil
+	}
+	return &w.Colors[index-1]
+}
+
+type WangColor struct {
+	Name        string  `xml:"name,attr"`
+	Class       string  `xml:"class,attr"`
+	Color       string  `xml:"color,attr"`
+	Tile        int32   `xml:"tile,attr"`
+	Probability float64 `xml:"probability,attr"`
+
+	Properties Properties `xml:"properties>property"`
+}
+
+func (c *WangColor) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type wangColor WangColor
+	x := wangColor{Probability: 1}
+	err := d.DecodeElement(&x, &start)
+	if err != nil {
+		return err
+	}
+	*c = WangColor(x)
+	return nil
+}
+
+type WangTile struct {
+	TileID int32  `xml:"tileid,attr"`
+	WangID WangID `xml:"wangid,attr"`
+}
+
+// A WangID holds the color index of each edge and corner of a tile, clockwise
+// from the top edge: top, top-right, right, bottom-right, bottom, bottom-left,
+// left and top-left. Index 0 means no color.
+type WangID [8]uint8
+
+// UnmarshalXMLAttr parses a Wang ID as a comma-separated list of color indexes,
+// or in the hexadecimal form written by Tiled before version 1.5.
+func (w *WangID) UnmarshalXMLAttr(attr xml.Attr) error {
+	s := attr.Value
+	if strings.HasPrefix(s, "0x") {
+		n, err := strconv.ParseUint(s[2:], 16, 32)
+		if err != nil {
+			return fmt.Errorf("tmx: malformed wangid %q", s)
+		}
+		for i := range w {
+			w[i] = uint8(n >> (4 * i) & 0xf)
+		}
+		return nil
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) != len(w) {
+		return fmt.Errorf("tmx: malformed wangid %q", s)
+	}
+	for i, p := range parts {
+		n, err := strconv.ParseUint(strings.TrimSpace(p), 10, 8)
+		if err != nil {
+			return fmt.Errorf("tmx: malformed wangid %q", s)
+		}
+		w[i] = uint8(n)
+	}
+	return nil
+}
+
+// String returns w in the comma-separated form.
+func (w WangID) String() string {
+	parts := make([]string, len(w))
+	for i, c := range w {
+		parts[i] = strconv.Itoa(int(c))
+	}
+	return strings.Join(parts, ",")
+}
+
+// TerrainCorners parses the terrain attribute used by Tiled before version 1.5.
+// It returns the index into the tileset's TerrainTypes of the top-left, top-right,
+// bottom-left and bottom-right corners of t, with -1 for corners without terrain.
+func (t *Tile) TerrainCorners() ([4]int, error) {
+	corners := [4]int{-1, -1, -1, -1}
+	if t.Terrain == "" {
+		return corners, nil
+	}
+
+	parts := strings.Split(t.Terrain, ",")
+	if len(parts) != len(corners) {
+		return corners, fmt.Errorf("tmx: malformed terrain %q", t.Terrain)
+	}
+	for i, p := range parts {
+		if p == "" {
+			continue
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return corners, fmt.Errorf("tmx: malformed terrain %q", t.Terrain)
+		}
+		corners[i] = n
+	}
+	return corners, nil
+}
@@ -0,0 +1,158 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWangSets(t *testing.T) {
+	m, err := Decode(strings.NewReader(testWang))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	ws := m.Tilesets[0].WangSets
+	if len(ws) != 1 {
+		t.Fatalf("expected 1 wang set, got %d", len(ws))
+	}
+	w := &ws[0]
+	if w.Name != "Ground" || w.Type != "corner" || w.Tile != -1 || w.Properties.Get("auto") == nil {
+		t.Fatalf("wrong wang set: %+v", w)
+	}
+
+	if len(w.Colors) != 2 {
+		t.Fatalf("expected 2 colors, got %d", len(w.Colors))
+	}
+	grass, dirt := w.Color(1), w.Color(2)
+	if grass.Name != "Grass" || grass.Color != "#00ff00" || grass.Tile != 0 || grass.Probability != 1 {
+		t.Errorf("wrong first color: %+v", grass)
+	}
+	if dirt.Name != "Dirt" || dirt.Probability != 0.5 || dirt.Properties.Get("footstep").Value != "dirt.wav" {
+		t.Errorf("wrong second color: %+v", dirt)
+	}
+	if w.Color(0) != nil || w.Color(3) != nil {
+		t.Errorf("expected no colors outside 1 and 2")
+	}
+
+	id, ok := w.WangID(1)
+	if !ok || id != (WangID{0, 1, 0, 2, 0, 2, 0, 1}) {
+		t.Errorf("got %v, %v for tile 1", id, ok)
+	}
+	if _, ok := w.WangID(5); ok {
+		t.Errorf("expected tile 5 not to be in the set")
+	}
+}
+
+func TestLegacyWangID(t *testing.T) {
+	m, err := Decode(strings.NewReader(strings.Replace(testWang, `wangid="0,1,0,2,0,2,0,1"`, `wangid="0x10202010"`, 1)))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	id, _ := m.Tilesets[0].WangSets[0].WangID(1)
+	if id != (WangID{0, 1, 0, 2, 0, 2, 0, 1}) {
+		t.Errorf("got %v", id)
+	}
+
+	_, err = Decode(strings.NewReader(strings.Replace(testWang, `wangid="0,1,0,2,0,2,0,1"`, `wangid="0,1,0"`, 1)))
+	if err == nil {
+		t.Errorf("expected error for a short wangid")
+	}
+}
+
+func TestLegacyWangColors(t *testing.T) {
+	m, err := Decode(strings.NewReader(testLegacyWang))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	w := &m.Tilesets[0].WangSets[0]
+	if w.Type != "mixed" {
+		t.Errorf("got type %q, expected mixed", w.Type)
+	}
+	var names []string
+	for _, c := range w.Colors {
+		names = append(names, c.Name)
+	}
+	if strings.Join(names, ",") != "Road,Grass,Water" {
+		t.Fatalf("got colors %v", names)
+	}
+	if w.Color(3).Color != "#0000ff" || w.Color(3).Probability != 1 || w.Color(1).Probability != 0.5 {
+		t.Errorf("wrong colors: %+v", w.Colors)
+	}
+	id, _ := w.WangID(0)
+	if id != (WangID{1, 2, 0, 3, 1, 3, 0, 2}) {
+		t.Errorf("got %v", id)
+	}
+}
+
+func TestTerrainCorners(t *testing.T) {
+	tests := []struct {
+		terrain string
+		want    [4]int
+	}{
+		{"", [4]int{-1, -1, -1, -1}},
+		{"0,0,0,0", [4]int{0, 0, 0, 0}},
+		{"0,,1,2", [4]int{0, -1, 1, 2}},
+		{",,,3", [4]int{-1, -1, -1, 3}},
+	}
+	for _, test := range tests {
+		tile := Tile{Terrain: test.terrain}
+		got, err := tile.TerrainCorners()
+		if err != nil || got != test.want {
+			t.Errorf("TerrainCorners(%q) = %v, %v, expected %v", test.terrain, got, err, test.want)
+		}
+	}
+
+	for _, bad := range []string{"0,0,0", "a,0,0,0", "-1,0,0,0"} {
+		tile := Tile{Terrain: bad}
+		if _, err := tile.TerrainCorners(); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+var testWang = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.9" tiledversion="1.9.2" orientation="orthogonal" renderorder="right-down" width="1" height="1" tilewidth="16" tileheight="16" infinite="0" nextlayerid="2" nextobjectid="1">
+ <tileset firstgid="1" name="land" tilewidth="16" tileheight="16" tilecount="4" columns="4">
+  <image source="land.png" width="64" height="16"/>
+  <wangsets>
+   <wangset name="Ground" type="corner" tile="-1">
+    <properties>
+     <property name="auto" type="bool" value="true"/>
+    </properties>
+    <wangcolor name="Grass" color="#00ff00" tile="0"/>
+    <wangcolor name="Dirt" color="#804000" tile="3" probability="0.5">
+     <properties>
+      <property name="footstep" type="file" value="dirt.wav"/>
+     </properties>
+    </wangcolor>
+    <wangtile tileid="0" wangid="0,1,0,1,0,1,0,1"/>
+    <wangtile tileid="1" wangid="0,1,0,2,0,2,0,1"/>
+    <wangtile tileid="3" wangid="0,2,0,2,0,2,0,2"/>
+   </wangset>
+  </wangsets>
+ </tileset>
+ <layer id="1" name="Ground" width="1" height="1">
+  <data encoding="csv">
+1
+</data>
+ </layer>
+</map>
+`
+
+var testLegacyWang = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.4" tiledversion="1.4.3" orientation="orthogonal" renderorder="right-down" width="1" height="1" tilewidth="16" tileheight="16" infinite="0" nextlayerid="1" nextobjectid="1">
+ <tileset firstgid="1" name="land" tilewidth="16" tileheight="16" tilecount="4" columns="4">
+  <image source="land.png" width="64" height="16"/>
+  <wangsets>
+   <wangset name="Paths" tile="-1">
+    <wangcornercolor name="Grass" color="#00ff00" tile="0" probability="1"/>
+    <wangcornercolor name="Water" color="#0000ff" tile="1" probability="1"/>
+    <wangedgecolor name="Road" color="#808080" tile="2" probability="0.5"/>
+    <wangtile tileid="0" wangid="0x10212011"/>
+   </wangset>
+  </wangsets>
+ </tileset>
+</map>
+`
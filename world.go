@@ -0,0 +1,171 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+)
+
+// A World is a Tiled world file, which lays out maps next to each other.
+type World struct {
+	Maps                 []WorldMap     `json:"maps"`
+	Patterns             []WorldPattern `json:"patterns"`
+	OnlyShowAdjacentMaps bool           `json:"onlyShowAdjacentMaps"`
+}
+
+// A WorldMap is a map placed in a world, in pixels.
+type WorldMap struct {
+	FileName string `json:"fileName"`
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+
+	// The map, once it is loaded by DecodeWorldFile.
+	Map *Map `json:"-"`
+}
+
+// A WorldPattern places each map whose file name matches Regexp by the numbers
+// in the regular expression's first two groups, as x and y.
+type WorldPattern struct {
+	Regexp      string `json:"regexp"`
+	MultiplierX int    `json:"multiplierX"`
+	MultiplierY int    `json:"multiplierY"`
+	OffsetX     int    `json:"offsetX"`
+	OffsetY     int    `json:"offsetY"`
+	MapWidth    int    `json:"mapWidth"`
+	MapHeight   int    `json:"mapHeight"`
+}
+
+// DecodeWorld decodes a world file, without looking for the maps that its patterns match.
+func DecodeWorld(r io.Reader) (*World, error) {
+	var w struct {
+		World
+		Type string `json:"type"`
+	}
+	err := json.NewDecoder(r).Decode(&w)
+	if err != nil {
+		return nil, err
+	}
+	if w.Type != "" && w.Type != "world" {
+		return nil, fmt.Errorf("tmx: JSON document is a %s, not a world", w.Type)
+	}
+	return &w.World, nil
+}
+
+// DecodeWorldFile decodes the world file named name, opened through res, and loads each of its maps
+// with DecodeFile. Maps that match its patterns are added after the listed ones, which requires
+// res to be a DirResolver.
+func DecodeWorldFile(name string, res Resolver) (*World, error) {
+	f, err := res.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("tmx: %w", err)
+	}
+	defer f.Close()
+
+	w, err := DecodeWorld(f)
+	if err != nil {
+		return nil, fmt.Errorf("tmx: %s: %w", name, err)
+	}
+	err = w.matchPatterns(name, res)
+	if err != nil {
+		return nil, fmt.Errorf("tmx: %s: %w", name, err)
+	}
+
+	for i := range w.Maps {
+		wm := &w.Maps[i]
+		wm.Map, err = DecodeFile(resolvePath(name, wm.FileName), res)
+		if err != nil {
+			return nil, err
+		}
+		if wm.Width == 0 && wm.Height == 0 {
+			wm.Width = wm.Map.Width * wm.Map.TileWidth
+			wm.Height = wm.Map.Height * wm.Map.TileHeight
+		}
+	}
+	return w, nil
+}
+
+// matchPatterns adds a map for each file in the directory of the world file name
+// that matches one of w's patterns.
+func (w *World) matchPatterns(name string, res Resolver) error {
+	if len(w.Patterns) == 0 {
+		return nil
+	}
+	dr, ok := res.(DirResolver)
+	if !ok {
+		return fmt.Errorf("cannot list files for patterns")
+	}
+	entries, err := dr.ReadDir(path.Dir(name))
+	if err != nil {
+		return err
+	}
+
+	for _, p := range w.Patterns {
+		re, err := regexp.Compile(p.Regexp)
+		if err != nil {
+			return err
+		}
+		if re.NumSubexp() < 2 {
+			return fmt.Errorf("pattern %q has no groups for x and y", p.Regexp)
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			match := re.FindStringSubmatch(e.Name())
+			if match == nil {
+				continue
+			}
+			x, err := strconv.Atoi(match[1])
+			if err != nil {
+				return fmt.Errorf("pattern %q: %w", p.Regexp, err)
+			}
+			y, err := strconv.Atoi(match[2])
+			if err != nil {
+				return fmt.Errorf("pattern %q: %w", p.Regexp, err)
+			}
+			w.Maps = append(w.Maps, WorldMap{
+				FileName: e.Name(),
+				X:        x*p.MultiplierX + p.OffsetX,
+				Y:        y*p.MultiplierY + p.OffsetY,
+				Width:    p.MapWidth,
+				Height:   p.MapHeight,
+			})
+		}
+	}
+	return nil
+}
+
+// Bounds returns the rectangle that m covers in its world, in pixels.
+func (m *WorldMap) Bounds() image.Rectangle {
+	return image.Rect(m.X, m.Y, m.X+m.Width, m.Y+m.Height)
+}
+
+// MapsIn returns the maps that overlap r, in world pixels, in the order of w.Maps.
+func (w *World) MapsIn(r image.Rectangle) []*WorldMap {
+	var maps []*WorldMap
+	for i := range w.Maps {
+		if w.Maps[i].Bounds().Overlaps(r) {
+			maps = append(maps, &w.Maps[i])
+		}
+	}
+	return maps
+}
+
+// MapAt returns the first map that contains the world pixel x, y, or nil if there is none.
+func (w *World) MapAt(x, y int) *WorldMap {
+	p := image.Pt(x, y)
+	for i := range w.Maps {
+		if p.In(w.Maps[i].Bounds()) {
+			return &w.Maps[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+// © 2016 Steve McCoy under the MIT license. See LICENSE for details.
+
+package tmx
+
+import (
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDecodeWorldFile(t *testing.T) {
+	w, err := DecodeWorldFile("world/overworld.world", FS(testWorldFS()))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	want := []WorldMap{
+		{FileName: "town.tmx", X: -160, Y: 0, Width: 160, Height: 160},
+		{FileName: "../caves/cave.tmx", X: 0, Y: 320, Width: 160, Height: 160},
+		{FileName: "room-0-0.tmx", X: 0, Y: 0, Width: 160, Height: 160},
+		{FileName: "room-1-0.tmx", X: 160, Y: 0, Width: 160, Height: 160},
+		{FileName: "room-1-2.tmx", X: 160, Y: 320, Width: 160, Height: 160},
+	}
+	if len(w.Maps) != len(want) {
+		t.Fatalf("got %d maps, expected %d: %+v", len(w.Maps), len(want), w.Maps)
+	}
+	for i := range want {
+		got := w.Maps[i]
+		if got.Map == nil || got.Map.Width != 10 {
+			t.Errorf("map %d wasn't loaded: %+v", i, got.Map)
+		}
+		got.Map = nil
+		if got != want[i] {
+			t.Errorf("map %d: got %+v, expected %+v", i, got, want[i])
+		}
+	}
+
+	at := []struct {
+		x, y int
+		name string
+	}{
+		{-1, 5, "town.tmx"},
+		{0, 0, "room-0-0.tmx"},
+		{170, 10, "room-1-0.tmx"},
+		{159, 479, "../caves/cave.tmx"},
+		{500, 500, ""},
+		{0, 200, ""},
+	}
+	for _, a := range at {
+		m := w.MapAt(a.x, a.y)
+		name := ""
+		if m != nil {
+			name = m.FileName
+		}
+		if name != a.name {
+			t.Errorf("MapAt(%d, %d) = %q, expected %q", a.x, a.y, name, a.name)
+		}
+	}
+
+	var names []string
+	for _, m := range w.MapsIn(image.Rect(150, 150, 170, 330)) {
+		names = append(names, m.FileName)
+	}
+	if strings.Join(names, " ") != "../caves/cave.tmx room-0-0.tmx room-1-0.tmx room-1-2.tmx" {
+		t.Errorf("wrong maps in rectangle: %v", names)
+	}
+	if maps := w.MapsIn(image.Rect(1000, 1000, 2000, 2000)); maps != nil {
+		t.Errorf("expected no maps, got %v", maps)
+	}
+}
+
+func TestDecodeWorldFileDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, f := range testWorldFS() {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(name), 0777)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(name, f.Data, 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := DecodeWorldFile("world/overworld.world", Dir(dir))
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if len(w.Maps) != 5 {
+		t.Fatalf("expected 5 maps, got %+v", w.Maps)
+	}
+}
+
+func TestBadWorld(t *testing.T) {
+	fsys := testWorldFS()
+	noList := ResolverFunc(func(name string) (io.ReadCloser, error) {
+		return fsys.Open(name)
+	})
+	_, err := DecodeWorldFile("world/overworld.world", noList)
+	if err == nil {
+		t.Errorf("expected error for patterns without a DirResolver")
+	}
+
+	for _, re := range []string{`room-(\\d+`, `room-(\\d+)\\.tmx`, `room-(\\w+)-(\\d+)\\.tmx`} {
+		fsys := testWorldFS()
+		fsys["world/overworld.world"].Data = []byte(strings.Replace(testWorld, `room-(\\d+)-(\\d+)\\.tmx`, re, 1))
+		_, err = DecodeWorldFile("world/overworld.world", FS(fsys))
+		if err == nil {
+			t.Errorf("expected error for pattern %s", re)
+		}
+	}
+
+	fsys = testWorldFS()
+	delete(fsys, "world/town.tmx")
+	_, err = DecodeWorldFile("world/overworld.world", FS(fsys))
+	if err == nil {
+		t.Errorf("expected error for a missing map")
+	}
+
+	_, err = DecodeWorld(strings.NewReader(`{"type": "map"}`))
+	if err == nil {
+		t.Errorf("expected error for a map as a world")
+	}
+}
+
+func testWorldFS() fstest.MapFS {
+	return fstest.MapFS{
+		"world/overworld.world":  {Data: []byte(testWorld)},
+		"world/town.tmx":         {Data: []byte(testCsv)},
+		"world/room-0-0.tmx":     {Data: []byte(testCsv)},
+		"world/room-1-0.tmx":     {Data: []byte(testCsv)},
+		"world/room-1-2.tmx":     {Data: []byte(testCsv)},
+		"world/room-x-2.tmx":     {Data: []byte(testCsv)},
+		"world/notes.txt":        {Data: []byte("room-9-9.tmx is next")},
+		"world/old/room-5-5.tmx": {Data: []byte(testCsv)},
+		"caves/cave.tmx":         {Data: []byte(testCsv)},
+	}
+}
+
+var testWorld = `{
+    "maps": [
+        {
+            "fileName": "town.tmx",
+            "height": 160,
+            "width": 160,
+            "x": -160,
+            "y": 0
+        },
+        {
+            "fileName": "../caves/cave.tmx",
+            "x": 0,
+            "y": 320
+        }
+    ],
+    "patterns": [
+        {
+            "regexp": "^room-(\\d+)-(\\d+)\\.tmx$",
+            "multiplierX": 160,
+            "multiplierY": 160,
+            "offsetX": 0,
+            "offsetY": 0,
+            "mapWidth": 160,
+            "mapHeight": 160
+        }
+    ],
+    "onlyShowAdjacentMaps": false,
+    "type": "world"
+}
+`